@@ -0,0 +1,55 @@
+// Package buildinfo expone con qué versión del código se compiló el binario.
+//
+// Commit y Time se pueden fijar al compilar:
+//
+//	go build -ldflags "-X tpeweb.com/servidor-go/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X tpeweb.com/servidor-go/buildinfo.Time=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Si no se fijan, se usan los datos de git que go build guarda en el binario.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	Commit string
+	Time   string
+)
+
+type Info struct {
+	Commit    string `json:"commit"`
+	Time      string `json:"build_time"`
+	Modified  bool   `json:"modified"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get devuelve la información de compilación; los campos que no se conocen
+// quedan como "unknown".
+func Get() Info {
+	info := Info{Commit: Commit, Time: Time, GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.Time == "" {
+					info.Time = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.Time == "" {
+		info.Time = "unknown"
+	}
+	return info
+}
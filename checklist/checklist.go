@@ -0,0 +1,62 @@
+// Package checklist pasa el cuerpo de una nota a una lista de ítems y al
+// revés.
+package checklist
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MaxIndent es la sangría máxima de un ítem: como en Keep, un solo nivel.
+const MaxIndent = 1
+
+// Item es un ítem de una checklist, sin su posición.
+type Item struct {
+	Text    string
+	Checked bool
+	Indent  int32
+}
+
+// line reconoce una línea de lista: sangría opcional, viñeta opcional (-, *
+// o +) y casilla opcional como en las listas de tareas de Markdown.
+var line = regexp.MustCompile(`^([ \t]*)(?:[-*+][ \t]+)?(?:\[([ xX])\](?:[ \t]+|$))?(.*)$`)
+
+// Parse arma un ítem por cada línea de body que no esté vacía. Entiende
+// tanto texto suelto como lo que genera Format, así una nota puede ir y
+// volver sin perder qué estaba tildado ni la sangría.
+func Parse(body string) []Item {
+	var items []Item
+	for _, text := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		m := line.FindStringSubmatch(text)
+		item := Item{
+			Text:    strings.TrimSpace(m[3]),
+			Checked: m[2] == "x" || m[2] == "X",
+		}
+		// Dos espacios o un tab cuentan como un nivel
+		if width := len(strings.ReplaceAll(m[1], "\t", "  ")); width >= 2 {
+			item.Indent = MaxIndent
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// Format escribe los ítems como una lista de tareas de Markdown, con dos
+// espacios por nivel de sangría.
+func Format(items []Item) string {
+	var b strings.Builder
+	for _, item := range items {
+		b.WriteString(strings.Repeat("  ", int(item.Indent)))
+		if item.Checked {
+			b.WriteString("- [x] ")
+		} else {
+			b.WriteString("- [ ] ")
+		}
+		b.WriteString(item.Text)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,277 @@
+// Package config arma la configuración del servidor a partir de valores por
+// defecto, un archivo YAML opcional y variables de entorno, en ese orden de
+// prioridad (las variables de entorno pisan al archivo).
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	// ListenAddr es la dirección donde escucha el servidor HTTP, por ejemplo ":8080".
+	ListenAddr string `yaml:"listen_addr"`
+	// StaticDir es la carpeta con el frontend.
+	StaticDir string     `yaml:"static_dir"`
+	LogLevel  slog.Level `yaml:"log_level"`
+	Database  Database   `yaml:"database"`
+	HTTP      HTTP       `yaml:"http"`
+
+	// TrashRetention es cuánto tiempo queda algo en la papelera antes de
+	// borrarse definitivamente.
+	TrashRetention time.Duration `yaml:"trash_retention"`
+	// NoteRevisionsMax es la cantidad de revisiones que se guardan por nota;
+	// con 0 no se borran nunca.
+	NoteRevisionsMax int `yaml:"note_revisions_max"`
+	// CookieSecure marca la cookie de sesión como Secure, para que el
+	// navegador solo la mande por HTTPS. Se apaga solo para servir por HTTP
+	// plano fuera de localhost.
+	CookieSecure bool `yaml:"cookie_secure"`
+}
+
+// Drivers de base soportados.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+type Database struct {
+	// Driver es "postgres" o "sqlite". Con sqlite, URL es la ruta del archivo.
+	Driver          string        `yaml:"driver"`
+	URL             string        `yaml:"url"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	// ConnectTimeout limita el ping inicial a la base.
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+	// MigrateOnStart aplica las migraciones pendientes al arrancar el
+	// servidor. Sin esto hay que correr "migrate up" a mano.
+	MigrateOnStart bool `yaml:"migrate_on_start"`
+}
+
+type HTTP struct {
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes"`
+	// ShutdownTimeout es cuánto se espera a que terminen las requests en
+	// curso al recibir SIGINT o SIGTERM antes de cortarlas.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// Default devuelve la configuración por defecto. No trae DSN: la base se
+// configura siempre con DATABASE_URL o con el archivo.
+func Default() Config {
+	return Config{
+		ListenAddr: ":8080",
+		StaticDir:  "./static",
+		LogLevel:   slog.LevelInfo,
+		Database: Database{
+			Driver:          DriverPostgres,
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnectTimeout:  5 * time.Second,
+			MigrateOnStart:  true,
+		},
+		HTTP: HTTP{
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			MaxHeaderBytes:    1 << 20,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		TrashRetention:   30 * 24 * time.Hour,
+		NoteRevisionsMax: 50,
+		CookieSecure:     true,
+	}
+}
+
+// Load arma la configuración. path es el archivo YAML; si viene vacío se usa
+// CONFIG_FILE, y si tampoco está definido solo se leen las variables de entorno.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return cfg, err
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// loadFile pisa los valores con los del archivo. Un campo desconocido es un
+// error, así un typo no pasa desapercibido.
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("no se pudo leer el archivo de configuración: %w", err)
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv pisa los valores con las variables de entorno que estén definidas.
+func (c *Config) applyEnv() error {
+	return errors.Join(
+		envString("LISTEN_ADDR", &c.ListenAddr),
+		envString("STATIC_DIR", &c.StaticDir),
+		envLogLevel("LOG_LEVEL", &c.LogLevel),
+		envString("DB_DRIVER", &c.Database.Driver),
+		envString("DATABASE_URL", &c.Database.URL),
+		envInt("DB_MAX_OPEN_CONNS", &c.Database.MaxOpenConns),
+		envInt("DB_MAX_IDLE_CONNS", &c.Database.MaxIdleConns),
+		envDuration("DB_CONN_MAX_LIFETIME", &c.Database.ConnMaxLifetime),
+		envDuration("DB_CONNECT_TIMEOUT", &c.Database.ConnectTimeout),
+		envBool("DB_MIGRATE_ON_START", &c.Database.MigrateOnStart),
+		envDuration("HTTP_READ_HEADER_TIMEOUT", &c.HTTP.ReadHeaderTimeout),
+		envDuration("HTTP_READ_TIMEOUT", &c.HTTP.ReadTimeout),
+		envDuration("HTTP_WRITE_TIMEOUT", &c.HTTP.WriteTimeout),
+		envDuration("HTTP_IDLE_TIMEOUT", &c.HTTP.IdleTimeout),
+		envInt("HTTP_MAX_HEADER_BYTES", &c.HTTP.MaxHeaderBytes),
+		envDuration("HTTP_SHUTDOWN_TIMEOUT", &c.HTTP.ShutdownTimeout),
+		envDuration("TRASH_RETENTION", &c.TrashRetention),
+		envInt("NOTE_REVISIONS_MAX", &c.NoteRevisionsMax),
+		envBool("COOKIE_SECURE", &c.CookieSecure),
+	)
+}
+
+// Validate junta todos los valores inválidos en un solo error.
+func (c Config) Validate() error {
+	var errs []error
+	if c.ListenAddr == "" {
+		errs = append(errs, errors.New("listen_addr es obligatorio"))
+	}
+	if info, err := os.Stat(c.StaticDir); err != nil || !info.IsDir() {
+		errs = append(errs, fmt.Errorf("static_dir %q no es una carpeta", c.StaticDir))
+	}
+	if c.Database.Driver != DriverPostgres && c.Database.Driver != DriverSQLite {
+		errs = append(errs, fmt.Errorf("database.driver %q no existe: tiene que ser %s o %s", c.Database.Driver, DriverPostgres, DriverSQLite))
+	}
+	if c.Database.URL == "" {
+		errs = append(errs, errors.New("falta la URL de la base (DATABASE_URL o database.url)"))
+	} else if c.Database.Driver == DriverPostgres {
+		if err := checkPostgresDSN(c.Database.URL); err != nil {
+			errs = append(errs, fmt.Errorf("database.url no es un DSN de Postgres válido: %w", err))
+		}
+	}
+	if c.Database.MaxOpenConns < 0 {
+		errs = append(errs, errors.New("database.max_open_conns no puede ser negativo"))
+	}
+	if c.Database.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("database.max_idle_conns no puede ser negativo"))
+	}
+	if c.Database.ConnMaxLifetime < 0 {
+		errs = append(errs, errors.New("database.conn_max_lifetime no puede ser negativo"))
+	}
+	if c.Database.ConnectTimeout <= 0 {
+		errs = append(errs, errors.New("database.connect_timeout tiene que ser mayor a 0"))
+	}
+	if c.HTTP.ReadHeaderTimeout <= 0 || c.HTTP.ReadTimeout <= 0 || c.HTTP.WriteTimeout <= 0 ||
+		c.HTTP.IdleTimeout <= 0 || c.HTTP.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("los timeouts de http tienen que ser mayores a 0"))
+	}
+	if c.HTTP.MaxHeaderBytes < 4096 {
+		errs = append(errs, errors.New("http.max_header_bytes tiene que ser al menos 4096"))
+	}
+	if c.TrashRetention <= 0 {
+		errs = append(errs, errors.New("trash_retention tiene que ser mayor a 0"))
+	}
+	if c.NoteRevisionsMax < 0 {
+		errs = append(errs, errors.New("note_revisions_max no puede ser negativo"))
+	}
+	return errors.Join(errs...)
+}
+
+// Redacted devuelve una copia sin secretos, para mostrarla o loguearla. Con
+// sqlite la URL es una ruta y se deja como está.
+func (c Config) Redacted() Config {
+	if c.Database.Driver != DriverSQLite && c.Database.URL != "" {
+		c.Database.URL = redactPostgresDSN(c.Database.URL)
+	}
+	return c
+}
+
+// YAML devuelve la configuración en el mismo formato que el archivo.
+func (c Config) YAML() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+func envString(name string, dst *string) error {
+	if value, ok := os.LookupEnv(name); ok {
+		*dst = value
+	}
+	return nil
+}
+
+func envInt(name string, dst *int) error {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%s inválido: %q", name, value)
+	}
+	*dst = n
+	return nil
+}
+
+func envBool(name string, dst *bool) error {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%s inválido: %q", name, value)
+	}
+	*dst = b
+	return nil
+}
+
+func envDuration(name string, dst *time.Duration) error {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%s inválido: %q", name, value)
+	}
+	*dst = d
+	return nil
+}
+
+func envLogLevel(name string, dst *slog.Level) error {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return nil
+	}
+	if err := dst.UnmarshalText([]byte(value)); err != nil {
+		return fmt.Errorf("%s inválido: %q", name, value)
+	}
+	return nil
+}
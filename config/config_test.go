@@ -0,0 +1,71 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedacted(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver string
+		url    string
+		want   string
+	}{
+		{"url con contraseña", DriverPostgres, "postgres://k:hunter2@db:5432/x?sslmode=disable", "postgres://k:xxxxx@db:5432/x?sslmode=disable"},
+		{"url sin contraseña", DriverPostgres, "postgres://k@db/x", "postgres://k@db/x"},
+		{"password en la query", DriverPostgres, "postgres://k@db/x?password=hunter2&sslmode=disable", "postgres://k@db/x?password=xxxxx&sslmode=disable"},
+		{"clave=valor", DriverPostgres, "host=db user=k password=hunter2 dbname=x", "host=db user=k password=xxxxx dbname=x"},
+		{"clave=valor con comillas", DriverPostgres, "host=db password = 'hun ter\\'2' dbname=x", "host=db password=xxxxx dbname=x"},
+		{"clave=valor sin password", DriverPostgres, "host=db dbname='mis notas'", "host=db dbname='mis notas'"},
+		{"no se entiende", DriverPostgres, "host=db password='hunter2", "xxxxx"},
+		{"url rota", DriverPostgres, "postgres://k:hunter2@db:puerto/x", "xxxxx"},
+		{"sqlite", DriverSQLite, "keepnotes.db?_pragma=busy_timeout(1000)", "keepnotes.db?_pragma=busy_timeout(1000)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Database.Driver, cfg.Database.URL = tt.driver, tt.url
+			got := cfg.Redacted().Database.URL
+			if got != tt.want {
+				t.Fatalf("Redacted() = %q, quería %q", got, tt.want)
+			}
+			if strings.Contains(got, "hunter2") {
+				t.Fatalf("Redacted() deja ver la contraseña: %q", got)
+			}
+		})
+	}
+}
+
+func TestValidateDatabaseURL(t *testing.T) {
+	tests := []struct {
+		driver string
+		url    string
+		ok     bool
+	}{
+		{DriverPostgres, "postgres://k:hunter2@db:5432/x?sslmode=disable", true},
+		{DriverPostgres, "postgresql://db/x", true},
+		{DriverPostgres, "host=db user=k password=hunter2 dbname=x", true},
+		{DriverPostgres, "host=/var/run/postgresql dbname='mis notas'", true},
+		{DriverPostgres, "mysql://k@db/x", false},
+		{DriverPostgres, "postgres:///x", false},
+		{DriverPostgres, "postgres:///x?host=/var/run/postgresql", true},
+		{DriverPostgres, "keepnotes.db", false},
+		{DriverPostgres, "host=db dbname", false},
+		{DriverPostgres, "host='db", false},
+		{DriverSQLite, "keepnotes.db", true},
+		{DriverSQLite, "/tmp/notas.db?_pragma=busy_timeout(1000)", true},
+	}
+	for _, tt := range tests {
+		cfg := Default()
+		cfg.StaticDir = t.TempDir()
+		cfg.Database.Driver, cfg.Database.URL = tt.driver, tt.url
+		err := cfg.Validate()
+		if (err == nil) != tt.ok {
+			t.Errorf("Validate() con %s %q = %v", tt.driver, tt.url, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), "database.url") {
+			t.Errorf("Validate() con %q = %v, quería un error de database.url", tt.url, err)
+		}
+	}
+}
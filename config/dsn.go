@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// hidden reemplaza a la contraseña cuando se muestra el DSN, y al DSN
+// entero cuando no se puede interpretar.
+const hidden = "xxxxx"
+
+// dsnParam es un par clave=valor de un DSN de Postgres.
+type dsnParam struct {
+	key, value string
+}
+
+// isPostgresURL dice si el DSN tiene forma de URL (postgres://...) y no de
+// lista de clave=valor.
+func isPostgresURL(dsn string) bool {
+	return strings.Contains(dsn, "://")
+}
+
+// checkPostgresDSN valida los dos formatos que acepta lib/pq: una URL
+// postgres:// o postgresql:// con host (en la URL o, para un socket Unix, en
+// el parámetro host), o una lista de clave=valor.
+func checkPostgresDSN(dsn string) error {
+	if !isPostgresURL(dsn) {
+		_, err := parseKeywordValue(dsn)
+		return err
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return errors.New("no es una URL válida")
+	}
+	if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+		return errors.New("el esquema tiene que ser postgres:// o postgresql://")
+	}
+	if u.Host == "" && u.Query().Get("host") == "" {
+		return errors.New("falta el host")
+	}
+	return nil
+}
+
+// redactPostgresDSN oculta la contraseña en cualquiera de los dos formatos,
+// incluido el parámetro password de la URL. Si el DSN no se puede
+// interpretar no se muestra nada de él.
+func redactPostgresDSN(dsn string) string {
+	if !isPostgresURL(dsn) {
+		params, err := parseKeywordValue(dsn)
+		if err != nil {
+			return hidden
+		}
+		parts := make([]string, len(params))
+		for i, p := range params {
+			if p.key == "password" {
+				p.value = hidden
+			}
+			parts[i] = p.key + "=" + quoteDSNValue(p.value)
+		}
+		return strings.Join(parts, " ")
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return hidden
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), hidden)
+	}
+	if query := u.Query(); query.Has("password") {
+		query.Set("password", hidden)
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// parseKeywordValue interpreta un DSN como "host=db user=k password='a b'"
+// con las mismas reglas que libpq: pares separados por espacios, espacios
+// opcionales alrededor del =, valores entre comillas simples y \ para
+// escapar.
+func parseKeywordValue(dsn string) ([]dsnParam, error) {
+	var params []dsnParam
+	s := []rune(dsn)
+	i := 0
+	skipSpaces := func() {
+		for i < len(s) && isDSNSpace(s[i]) {
+			i++
+		}
+	}
+
+	for skipSpaces(); i < len(s); skipSpaces() {
+		start := i
+		for i < len(s) && s[i] != '=' && !isDSNSpace(s[i]) {
+			i++
+		}
+		key := string(s[start:i])
+		skipSpaces()
+		if key == "" || i == len(s) || s[i] != '=' {
+			return nil, errors.New("no es una URL postgres:// ni una lista de clave=valor")
+		}
+		i++
+		skipSpaces()
+
+		var value strings.Builder
+		quoted := i < len(s) && s[i] == '\''
+		if quoted {
+			i++
+		}
+		for ; i < len(s); i++ {
+			if quoted && s[i] == '\'' || !quoted && isDSNSpace(s[i]) {
+				break
+			}
+			if s[i] == '\\' {
+				i++
+				if i == len(s) {
+					return nil, errors.New("termina con un \\ suelto")
+				}
+			}
+			value.WriteRune(s[i])
+		}
+		if quoted {
+			if i == len(s) {
+				return nil, errors.New("tiene una comilla sin cerrar")
+			}
+			i++
+		}
+		params = append(params, dsnParam{key, value.String()})
+	}
+
+	if len(params) == 0 {
+		return nil, errors.New("está vacía")
+	}
+	return params, nil
+}
+
+func isDSNSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' || r == '\v'
+}
+
+// quoteDSNValue pone comillas al valor solo si hace falta.
+func quoteDSNValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n\r\f\v'\\") {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
@@ -1,19 +1,45 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"strings"
+
 	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+	"tpeweb.com/servidor-go/config"
 )
 
-const dbDriver = "postgres"
-const dbSource = "postgres://keepnotes:keepnotes@db:5432/keepnotesdb?sslmode=disable"
+// sqlitePragmas se aplican a cada conexión de SQLite: sin foreign_keys no
+// andan los ON DELETE CASCADE, WAL deja leer mientras alguien escribe y
+// _txlock=immediate toma el lock de escritura al empezar la transacción, que
+// es lo más parecido al FOR UPDATE de Postgres.
+const sqlitePragmas = "_pragma=foreign_keys(1)&_pragma=journal_mode(wal)&_pragma=busy_timeout(5000)&_txlock=immediate"
 
-func ConnectDB() (*sql.DB, error) {
-	conn, err := sql.Open(dbDriver, dbSource)
+// ConnectDB abre el pool con los límites de cfg y verifica que la base
+// responda antes de ConnectTimeout. Los nombres de los drivers coinciden con
+// config.DriverPostgres y config.DriverSQLite.
+func ConnectDB(cfg config.Database) (*sql.DB, error) {
+	dsn := cfg.URL
+	if cfg.Driver == config.DriverSQLite {
+		if strings.Contains(dsn, "?") {
+			dsn += "&" + sqlitePragmas
+		} else {
+			dsn += "?" + sqlitePragmas
+		}
+	}
+	conn, err := sql.Open(cfg.Driver, dsn)
 	if err != nil {
 		return nil, err
 	}
-	if err := conn.Ping(); err != nil {
+	conn.SetMaxOpenConns(cfg.MaxOpenConns)
+	conn.SetMaxIdleConns(cfg.MaxIdleConns)
+	conn.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
+	defer cancel()
+	if err := conn.PingContext(ctx); err != nil {
+		conn.Close()
 		return nil, err
 	}
 	return conn, nil
@@ -0,0 +1,335 @@
+// Package migrations aplica los cambios de esquema versionados. Cada cambio
+// es un par de archivos NNNN_nombre.up.sql y NNNN_nombre.down.sql que se
+// embeben en el binario, en una carpeta por motor (postgres y sqlite); la
+// tabla schema_migrations guarda cuáles ya se aplicaron.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// lockKey identifica el advisory lock que toman las migraciones, para que dos
+// instancias que arrancan a la vez no apliquen lo mismo dos veces.
+const lockKey = 7_451_203_118
+
+// Dialect es lo que cambia entre motores: la carpeta con los scripts, cómo
+// se crea schema_migrations, cómo se pregunta si existe una tabla, el lock
+// que evita que dos procesos migren a la vez y si hay bases de antes de las
+// migraciones.
+type Dialect struct {
+	dir         string
+	createTable string
+	// tableExists recibe el nombre de la tabla como $1.
+	tableExists string
+	lock        string
+	unlock      string
+	// legacy indica que puede haber bases anteriores a las migraciones, con
+	// el esquema de 0001 ya creado (ver baseline).
+	legacy bool
+}
+
+var Postgres = &Dialect{
+	dir: "postgres",
+	createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version BIGINT PRIMARY KEY,
+  name TEXT NOT NULL,
+  applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`,
+	tableExists: `SELECT to_regclass($1) IS NOT NULL`,
+	lock:        `SELECT pg_advisory_lock($1)`,
+	unlock:      `SELECT pg_advisory_unlock($1)`,
+	legacy:      true,
+}
+
+// SQLite no tiene advisory locks: la base es de un solo proceso y cada
+// migración ya corre en una transacción que toma el lock de escritura.
+var SQLite = &Dialect{
+	dir: "sqlite",
+	createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version BIGINT PRIMARY KEY,
+  name TEXT NOT NULL,
+  applied_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+)`,
+	tableExists: `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = $1)`,
+}
+
+// ForDriver devuelve el dialecto del driver de database/sql configurado.
+func ForDriver(driver string) (*Dialect, error) {
+	switch driver {
+	case "postgres":
+		return Postgres, nil
+	case "sqlite":
+		return SQLite, nil
+	}
+	return nil, fmt.Errorf("no hay migraciones para el driver %q", driver)
+}
+
+type Migration struct {
+	Version int64
+	Name    string
+	up      string
+	down    string
+}
+
+// Status es una migración con la fecha en que se aplicó (nil si está pendiente).
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// load lee las migraciones embebidas del dialecto, ordenadas por versión.
+func (d *Dialect) load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, d.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migración con nombre inválido: %s", name)
+		}
+		number, label, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(number, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migración con nombre inválido: %s", name)
+		}
+		content, err := fs.ReadFile(files, d.dir+"/"+name)
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("a la migración %04d le falta el up o el down", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up aplica todas las migraciones pendientes, cada una en su transacción, y
+// devuelve las que aplicó.
+func (d *Dialect) Up(ctx context.Context, db *sql.DB) ([]Migration, error) {
+	var applied []Migration
+	err := d.withLock(ctx, db, func(conn *sql.Conn) error {
+		statuses, err := d.status(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			if s.AppliedAt != nil {
+				continue
+			}
+			if err := run(ctx, conn, s.Migration, s.up,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, s.Version, s.Name); err != nil {
+				return err
+			}
+			applied = append(applied, s.Migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down revierte las últimas steps migraciones aplicadas, de la más nueva a
+// la más vieja, y devuelve las que revirtió.
+func (d *Dialect) Down(ctx context.Context, db *sql.DB, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := d.withLock(ctx, db, func(conn *sql.Conn) error {
+		statuses, err := d.status(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(statuses) - 1; i >= 0 && len(reverted) < steps; i-- {
+			s := statuses[i]
+			if s.AppliedAt == nil {
+				continue
+			}
+			if err := run(ctx, conn, s.Migration, s.down,
+				`DELETE FROM schema_migrations WHERE version = $1`, s.Version); err != nil {
+				return err
+			}
+			reverted = append(reverted, s.Migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// List devuelve todas las migraciones con su estado.
+func (d *Dialect) List(ctx context.Context, db *sql.DB) ([]Status, error) {
+	var statuses []Status
+	err := d.withLock(ctx, db, func(conn *sql.Conn) error {
+		var err error
+		statuses, err = d.status(ctx, conn)
+		return err
+	})
+	return statuses, err
+}
+
+// Pending devuelve cuántas migraciones faltan aplicar. No toma el lock, así
+// se puede consultar desde un chequeo de salud mientras otra instancia migra.
+func (d *Dialect) Pending(ctx context.Context, db *sql.DB) (int, error) {
+	migrations, err := d.load()
+	if err != nil {
+		return 0, err
+	}
+	var exists bool
+	if err := db.QueryRowContext(ctx, d.tableExists, "schema_migrations").Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return len(migrations), nil
+	}
+
+	pending := 0
+	for _, m := range migrations {
+		var applied bool
+		if err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, m.Version).Scan(&applied); err != nil {
+			return 0, err
+		}
+		if !applied {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// withLock corre fn con el advisory lock tomado, sobre una sola conexión
+// (el lock es de la sesión, no del pool).
+func (d *Dialect) withLock(ctx context.Context, db *sql.DB, fn func(conn *sql.Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if d.lock != "" {
+		if _, err := conn.ExecContext(ctx, d.lock, lockKey); err != nil {
+			return fmt.Errorf("no se pudo tomar el lock de migraciones: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), d.unlock, lockKey)
+	}
+
+	if _, err := conn.ExecContext(ctx, d.createTable); err != nil {
+		return err
+	}
+	if err := d.baseline(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// baseline registra 0001 como aplicada en una base de Postgres creada con el
+// viejo db/schema, que cargaba docker-entrypoint-initdb.d: tiene las tablas
+// de 0001 pero no schema_migrations. Las migraciones siguientes la completan
+// con los datos que hagan falta.
+func (d *Dialect) baseline(ctx context.Context, conn *sql.Conn) error {
+	if !d.legacy {
+		return nil
+	}
+	var recorded bool
+	if err := conn.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations)`).Scan(&recorded); err != nil {
+		return err
+	}
+	var legacy bool
+	if err := conn.QueryRowContext(ctx, d.tableExists, "users").Scan(&legacy); err != nil {
+		return err
+	}
+	if recorded || !legacy {
+		return nil
+	}
+
+	migrations, err := d.load()
+	if err != nil {
+		return err
+	}
+	first := migrations[0]
+	if _, err := conn.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, first.Version, first.Name); err != nil {
+		return err
+	}
+	slog.Info("Base anterior a las migraciones: se registra como aplicada", "version", first.Version, "name", first.Name)
+	return nil
+}
+
+func (d *Dialect) status(ctx context.Context, conn *sql.Conn) ([]Status, error) {
+	migrations, err := d.load()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(migrations))
+	for _, m := range migrations {
+		s := Status{Migration: m}
+		if at, ok := applied[m.Version]; ok {
+			s.AppliedAt = &at
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// run ejecuta el SQL de la migración y actualiza schema_migrations en la
+// misma transacción.
+func run(ctx context.Context, conn *sql.Conn, m Migration, script, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("migración %04d_%s: %w", m.Version, m.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
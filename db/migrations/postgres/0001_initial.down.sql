@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS note;
+DROP TABLE IF EXISTS folder;
+DROP TABLE IF EXISTS users;
@@ -1,3 +1,8 @@
+-- El esquema que cargaba docker-entrypoint-initdb.d desde db/schema antes de
+-- que existieran las migraciones. Las bases creadas así tienen exactamente
+-- estas tablas: al migrarlas por primera vez se registra 0001 como aplicada
+-- y se sigue desde 0002.
+
 -- Users must be created first because folder references it
 CREATE TABLE users (
   id SERIAL PRIMARY KEY,
@@ -23,4 +28,4 @@ CREATE TABLE note (
   body TEXT,
   created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-);
\ No newline at end of file
+);
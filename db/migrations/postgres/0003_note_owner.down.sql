@@ -0,0 +1,4 @@
+-- El usuario "legacy" queda: borrarlo borraría en cascada lo que tiene.
+DROP INDEX IF EXISTS folder_user_id_idx;
+ALTER TABLE folder ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE note DROP COLUMN user_id;
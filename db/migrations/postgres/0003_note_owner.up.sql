@@ -0,0 +1,50 @@
+-- Cada carpeta y cada nota pasan a tener dueño. Antes folder.user_id podía
+-- quedar en NULL y note no tenía la columna, así que primero se completan
+-- los datos y recién después se pone NOT NULL:
+--
+-- 1. Una carpeta sin dueño toma el de la carpeta más cercana hacia arriba
+--    que lo tenga.
+-- 2. Una nota toma el dueño de su carpeta.
+-- 3. Lo que sigue sin dueño (carpetas sin ningún ancestro con dueño y notas
+--    sin carpeta) pasa al usuario "legacy", que se crea solo si hace falta.
+--    Su contraseña es el hash de un valor al azar que no se guardó, así que
+--    nadie puede entrar con él hasta que se le asigne una (ver README).
+ALTER TABLE note ADD COLUMN user_id INT REFERENCES users(id) ON DELETE CASCADE;
+
+WITH RECURSIVE inherited AS (
+    SELECT id, user_id
+    FROM folder
+    WHERE user_id IS NOT NULL
+  UNION
+    SELECT f.id, i.user_id
+    FROM folder f
+    JOIN inherited i ON f.parent_folder_id = i.id
+    WHERE f.user_id IS NULL
+)
+UPDATE folder f
+SET user_id = i.user_id
+FROM inherited i
+WHERE f.id = i.id AND f.user_id IS NULL;
+
+UPDATE note n
+SET user_id = f.user_id
+FROM folder f
+WHERE n.folder_id = f.id;
+
+INSERT INTO users (username, email, password)
+SELECT 'legacy', 'legacy@localhost',
+  '$argon2id$v=19$m=19456,t=2,p=1$hZhWLH9GqJPCwqQLnePw6A$X5uz1g0m69MEqHpctEfcslNo1PpgNkuGC5V8HZl0pCc'
+WHERE EXISTS (SELECT 1 FROM folder WHERE user_id IS NULL)
+   OR EXISTS (SELECT 1 FROM note WHERE user_id IS NULL);
+
+UPDATE folder SET user_id = (SELECT id FROM users WHERE username = 'legacy')
+WHERE user_id IS NULL;
+
+UPDATE note SET user_id = (SELECT id FROM users WHERE username = 'legacy')
+WHERE user_id IS NULL;
+
+ALTER TABLE folder ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE note ALTER COLUMN user_id SET NOT NULL;
+
+CREATE INDEX folder_user_id_idx ON folder (user_id);
+CREATE INDEX note_user_id_idx ON note (user_id);
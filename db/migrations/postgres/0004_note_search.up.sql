@@ -0,0 +1,9 @@
+-- Búsqueda full-text sobre título y cuerpo. La configuración 'simple' no
+-- saca stopwords ni hace stemming, así que anda igual en cualquier idioma;
+-- el título pesa más que el cuerpo.
+ALTER TABLE note ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+  setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+  setweight(to_tsvector('simple', coalesce(body, '')), 'B')
+) STORED;
+
+CREATE INDEX note_search_vector_idx ON note USING GIN (search_vector);
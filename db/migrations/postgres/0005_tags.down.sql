@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS note_tag;
+DROP TABLE IF EXISTS tag;
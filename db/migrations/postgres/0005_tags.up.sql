@@ -0,0 +1,16 @@
+-- Etiquetas por usuario, sin repetir el nombre dentro de la misma cuenta
+CREATE TABLE tag (
+  id SERIAL PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(50) NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (user_id, name)
+);
+
+CREATE TABLE note_tag (
+  note_id INT NOT NULL REFERENCES note(id) ON DELETE CASCADE,
+  tag_id INT NOT NULL REFERENCES tag(id) ON DELETE CASCADE,
+  PRIMARY KEY (note_id, tag_id)
+);
+
+CREATE INDEX note_tag_tag_id_idx ON note_tag (tag_id);
@@ -0,0 +1,2 @@
+ALTER TABLE note DROP COLUMN deleted_at;
+ALTER TABLE folder DROP COLUMN deleted_at;
@@ -0,0 +1,7 @@
+-- Papelera: deleted_at no nulo es lo que está en la papelera. Lo que ya
+-- existía queda en NULL, fuera de ella.
+ALTER TABLE folder ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE note ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX folder_deleted_at_idx ON folder (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX note_deleted_at_idx ON note (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,11 @@
+-- Estado anterior de una nota, guardado antes de cada actualización. Las
+-- notas que ya existían arrancan sin historial.
+CREATE TABLE note_revision (
+  id SERIAL PRIMARY KEY,
+  note_id INT NOT NULL REFERENCES note(id) ON DELETE CASCADE,
+  title VARCHAR(255) NOT NULL,
+  body TEXT,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX note_revision_note_id_idx ON note_revision (note_id, id);
@@ -0,0 +1,2 @@
+ALTER TABLE note DROP COLUMN version;
+ALTER TABLE folder DROP COLUMN version;
@@ -0,0 +1,4 @@
+-- Se incrementa en cada cambio; es el ETag de la carpeta o la nota. Las filas
+-- que ya existían arrancan en 1.
+ALTER TABLE folder ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE note ADD COLUMN version INT NOT NULL DEFAULT 1;
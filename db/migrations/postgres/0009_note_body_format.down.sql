@@ -0,0 +1,2 @@
+ALTER TABLE note_revision DROP COLUMN body_format;
+ALTER TABLE note DROP COLUMN body_format;
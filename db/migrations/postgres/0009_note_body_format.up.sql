@@ -0,0 +1,8 @@
+-- Formato del cuerpo de la nota: 'plain' (texto tal cual, como las notas
+-- que ya existían) o 'markdown' (CommonMark con las extensiones de GFM).
+ALTER TABLE note ADD COLUMN body_format VARCHAR(10) NOT NULL DEFAULT 'plain'
+  CHECK (body_format IN ('plain', 'markdown'));
+
+-- Las revisiones guardan también el formato, para restaurarlo junto con el cuerpo
+ALTER TABLE note_revision ADD COLUMN body_format VARCHAR(10) NOT NULL DEFAULT 'plain'
+  CHECK (body_format IN ('plain', 'markdown'));
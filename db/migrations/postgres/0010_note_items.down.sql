@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS note_item;
+ALTER TABLE note DROP COLUMN kind;
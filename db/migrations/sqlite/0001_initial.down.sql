@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS note_revision;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS note_tag;
+DROP TABLE IF EXISTS tag;
+DROP TABLE IF EXISTS note_fts;
+DROP TABLE IF EXISTS note;
+DROP TABLE IF EXISTS folder;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,99 @@
+-- El esquema de Postgres hasta 0008_versions, de una vez: SQLite llegó
+-- después, así que no hay bases de SQLite anteriores que completar de a un
+-- paso. Las migraciones siguientes usan los mismos números que en postgres/.
+-- Las fechas se guardan como texto en UTC (ver sqliteTimeFormat en
+-- store/sqlite.go), así se pueden comparar como strings.
+CREATE TABLE users (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  username VARCHAR(50) UNIQUE NOT NULL,
+  email VARCHAR(100) UNIQUE NOT NULL,
+  password VARCHAR(255) NOT NULL,
+  created_at DATETIME DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+CREATE TABLE folder (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(255) NOT NULL,
+  description TEXT,
+  parent_folder_id INT REFERENCES folder(id) ON DELETE CASCADE,
+  created_at DATETIME DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+  deleted_at DATETIME,
+  -- Se incrementa en cada cambio; es el ETag de la carpeta
+  version INT NOT NULL DEFAULT 1
+);
+
+CREATE TABLE note (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  folder_id INT REFERENCES folder(id) ON DELETE CASCADE,
+  title VARCHAR(255) NOT NULL,
+  body TEXT,
+  created_at DATETIME DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+  updated_at DATETIME DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+  deleted_at DATETIME,
+  -- Se incrementa en cada cambio; es el ETag de la nota
+  version INT NOT NULL DEFAULT 1
+);
+
+CREATE INDEX folder_user_id_idx ON folder (user_id);
+CREATE INDEX note_user_id_idx ON note (user_id);
+CREATE INDEX folder_deleted_at_idx ON folder (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX note_deleted_at_idx ON note (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- En lugar de search_vector: un índice FTS5 sobre título y cuerpo que leen
+-- de note y se mantiene con triggers. remove_diacritics 0 para cortar las
+-- palabras igual que la configuración 'simple' de Postgres.
+CREATE VIRTUAL TABLE note_fts USING fts5(
+  title, body,
+  content = 'note', content_rowid = 'id',
+  tokenize = 'unicode61 remove_diacritics 0'
+);
+
+CREATE TRIGGER note_fts_insert AFTER INSERT ON note BEGIN
+  INSERT INTO note_fts (rowid, title, body) VALUES (new.id, new.title, coalesce(new.body, ''));
+END;
+
+CREATE TRIGGER note_fts_delete AFTER DELETE ON note BEGIN
+  INSERT INTO note_fts (note_fts, rowid, title, body) VALUES ('delete', old.id, old.title, coalesce(old.body, ''));
+END;
+
+CREATE TRIGGER note_fts_update AFTER UPDATE OF title, body ON note BEGIN
+  INSERT INTO note_fts (note_fts, rowid, title, body) VALUES ('delete', old.id, old.title, coalesce(old.body, ''));
+  INSERT INTO note_fts (rowid, title, body) VALUES (new.id, new.title, coalesce(new.body, ''));
+END;
+
+CREATE TABLE tag (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(50) NOT NULL,
+  created_at DATETIME DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+  UNIQUE (user_id, name)
+);
+
+CREATE TABLE note_tag (
+  note_id INT NOT NULL REFERENCES note(id) ON DELETE CASCADE,
+  tag_id INT NOT NULL REFERENCES tag(id) ON DELETE CASCADE,
+  PRIMARY KEY (note_id, tag_id)
+);
+
+CREATE INDEX note_tag_tag_id_idx ON note_tag (tag_id);
+
+CREATE TABLE sessions (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  token_hash VARCHAR(64) UNIQUE NOT NULL,
+  created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+  expires_at DATETIME NOT NULL
+);
+
+-- Estado anterior de una nota, guardado antes de cada actualización
+CREATE TABLE note_revision (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  note_id INT NOT NULL REFERENCES note(id) ON DELETE CASCADE,
+  title VARCHAR(255) NOT NULL,
+  body TEXT,
+  created_at DATETIME DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+CREATE INDEX note_revision_note_id_idx ON note_revision (note_id, id);
@@ -0,0 +1,19 @@
+-- Tipo de nota: 'text' (título y cuerpo) o 'checklist' (una lista de ítems
+-- en note_item). En las checklists el cuerpo lo arma el servidor a partir de
+-- los ítems, como una lista de tareas en Markdown, para que la búsqueda y
+-- ?format=html sigan andando.
+ALTER TABLE note ADD COLUMN kind VARCHAR(10) NOT NULL DEFAULT 'text'
+  CHECK (kind IN ('text', 'checklist'));
+
+CREATE TABLE note_item (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  note_id INT NOT NULL REFERENCES note(id) ON DELETE CASCADE,
+  text TEXT NOT NULL DEFAULT '',
+  checked BOOLEAN NOT NULL DEFAULT FALSE,
+  -- Orden dentro de la nota, de menor a mayor; puede tener huecos
+  position INT NOT NULL,
+  -- Como en Keep, un ítem puede tener un solo nivel de sangría
+  indent INT NOT NULL DEFAULT 0 CHECK (indent IN (0, 1))
+);
+
+CREATE INDEX note_item_note_id_idx ON note_item (note_id, position);
@@ -0,0 +1,64 @@
+-- name: LockNote :one
+-- Bloquea la nota mientras se cambian sus ítems, así dos requests no
+-- calculan la misma posición ni arman el cuerpo con ítems viejos.
+SELECT kind
+FROM note
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+FOR UPDATE;
+
+-- name: ListNoteItems :many
+SELECT id, note_id, text, checked, position, indent
+FROM note_item
+WHERE note_id = $1
+ORDER BY position, id;
+
+-- name: GetNoteItem :one
+SELECT id, note_id, text, checked, position, indent
+FROM note_item
+WHERE id = $1 AND note_id = $2;
+
+-- name: NextNoteItemPosition :one
+SELECT COALESCE(MAX(position) + 1, 0)::int AS position
+FROM note_item
+WHERE note_id = $1;
+
+-- name: ShiftNoteItems :exec
+-- Corre un lugar los ítems desde position, para insertar uno ahí.
+UPDATE note_item
+SET position = position + 1
+WHERE note_id = $1 AND position >= $2;
+
+-- name: CreateNoteItem :one
+INSERT INTO note_item (note_id, text, checked, position, indent)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, note_id, text, checked, position, indent;
+
+-- name: UpdateNoteItem :one
+UPDATE note_item
+SET text = $3, checked = $4, indent = $5
+WHERE id = $1 AND note_id = $2
+RETURNING id, note_id, text, checked, position, indent;
+
+-- name: SetNoteItemPosition :execrows
+UPDATE note_item
+SET position = $3
+WHERE id = $1 AND note_id = $2;
+
+-- name: DeleteNoteItem :execrows
+DELETE FROM note_item
+WHERE id = $1 AND note_id = $2;
+
+-- name: DeleteNoteItems :exec
+DELETE FROM note_item
+WHERE note_id = $1;
+
+-- name: SetNoteContent :one
+-- Cambia el tipo y el cuerpo de la nota: al convertirla y, en las
+-- checklists, cada vez que cambian los ítems. Con version solo la cambia si
+-- sigue en esa versión.
+UPDATE note
+SET kind = @kind, body = sqlc.narg('body'), body_format = @body_format,
+  updated_at = CURRENT_TIMESTAMP, version = version + 1
+WHERE id = @id AND user_id = @user_id AND deleted_at IS NULL
+  AND (sqlc.narg('version')::int IS NULL OR version = sqlc.narg('version'))
+RETURNING id, user_id, folder_id, title, body, body_format, kind, created_at, updated_at, version;
@@ -0,0 +1,12 @@
+// Package queries embebe las variantes para SQLite de las queries de esta
+// carpeta. Las de Postgres las lee sqlc y el código queda en db/sqlc.
+package queries
+
+import "embed"
+
+// SQLite tiene, por nombre de query de sqlc, las que no se pueden traducir
+// solas al dialecto de SQLite. Los parámetros son ?N con el mismo número que
+// el $N de la query generada.
+//
+//go:embed sqlite/*.sql
+var SQLite embed.FS
@@ -1,56 +1,179 @@
 -- name: GetFolder :one
-SELECT id, user_id, name, description, parent_folder_id, created_at
+SELECT id, user_id, name, description, parent_folder_id, created_at, deleted_at, version
 FROM folder
-WHERE id = $1;
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
 
 -- name: GetNote :one
-SELECT id, folder_id, title, body, created_at, updated_at
+SELECT id, user_id, folder_id, title, body, body_format, kind, created_at, updated_at, version
 FROM note
-WHERE id = $1;
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
 
 -- name: ListFolders :many
-SELECT id, user_id, name, description, parent_folder_id, created_at
-FROM folder
-ORDER BY name;
-
--- name: ListFoldersByUser :many
-SELECT id, user_id, name, description, parent_folder_id, created_at
+-- Paginación por keyset: el cursor es el valor de la columna de orden y el
+-- id de la última fila de la página anterior.
+SELECT id, user_id, name, description, parent_folder_id, created_at, deleted_at, version
 FROM folder
-WHERE user_id = $1
-ORDER BY name;
+WHERE user_id = @user_id
+  AND deleted_at IS NULL
+  AND (sqlc.narg('parent_folder_id')::int IS NULL OR parent_folder_id = sqlc.narg('parent_folder_id'))
+  AND (sqlc.narg('created_after')::timestamptz IS NULL OR created_at > sqlc.narg('created_after'))
+  AND (
+    NOT @has_cursor::bool
+    OR (@sort_by::text = 'name' AND NOT @descending::bool AND (name, id) > (@cursor_text::text, @cursor_id::int))
+    OR (@sort_by::text = 'name' AND @descending::bool AND (name, id) < (@cursor_text::text, @cursor_id::int))
+    OR (@sort_by::text = 'created_at' AND NOT @descending::bool AND (created_at, id) > (@cursor_time::timestamptz, @cursor_id::int))
+    OR (@sort_by::text = 'created_at' AND @descending::bool AND (created_at, id) < (@cursor_time::timestamptz, @cursor_id::int))
+  )
+ORDER BY
+  CASE WHEN @sort_by::text = 'name' AND NOT @descending::bool THEN name END ASC,
+  CASE WHEN @sort_by::text = 'name' AND @descending::bool THEN name END DESC,
+  CASE WHEN @sort_by::text = 'created_at' AND NOT @descending::bool THEN created_at END ASC,
+  CASE WHEN @sort_by::text = 'created_at' AND @descending::bool THEN created_at END DESC,
+  CASE WHEN NOT @descending::bool THEN id END ASC,
+  CASE WHEN @descending::bool THEN id END DESC
+LIMIT @page_size;
+
+-- name: ListFolderTree :many
+-- Devuelve las carpetas del usuario con la cantidad de notas directas y
+-- la cantidad total incluyendo todas las subcarpetas. UNION (y no UNION
+-- ALL) descarta los pares repetidos, así que la recursión termina aunque
+-- los datos tengan un ciclo.
+WITH RECURSIVE subtree AS (
+    SELECT id AS ancestor_id, id AS folder_id
+    FROM folder
+    WHERE user_id = $1 AND deleted_at IS NULL
+  UNION
+    SELECT s.ancestor_id, f.id
+    FROM subtree s
+    JOIN folder f ON f.parent_folder_id = s.folder_id
+    WHERE f.deleted_at IS NULL
+)
+SELECT f.id, f.name, f.description, f.parent_folder_id, f.created_at,
+  (SELECT COUNT(*)
+   FROM note n
+   WHERE n.folder_id = f.id AND n.deleted_at IS NULL)::int AS note_count,
+  (SELECT COUNT(*)
+   FROM subtree s
+   JOIN note n ON n.folder_id = s.folder_id
+   WHERE s.ancestor_id = f.id AND n.deleted_at IS NULL)::int AS total_note_count
+FROM folder f
+WHERE f.user_id = $1 AND f.deleted_at IS NULL
+ORDER BY f.name;
+
+-- name: CountNotes :one
+SELECT COUNT(*) AS total,
+  COUNT(*) FILTER (WHERE folder_id IS NULL) AS unfiled
+FROM note
+WHERE user_id = $1 AND deleted_at IS NULL;
 
 -- name: ListNotes :many
-SELECT id, folder_id, title, body, created_at, updated_at
+-- Paginación por keyset: el cursor es el valor de la columna de orden y el
+-- id de la última fila de la página anterior. Si tags no está vacío, filtra
+-- las notas que tienen todas (match_all_tags) o alguna de esas etiquetas.
+SELECT id, user_id, folder_id, title, body, body_format, kind, created_at, updated_at, version
 FROM note
-ORDER BY title;
+WHERE user_id = @user_id
+  AND deleted_at IS NULL
+  AND (sqlc.narg('folder_id')::int IS NULL OR folder_id = sqlc.narg('folder_id'))
+  AND (sqlc.narg('created_after')::timestamptz IS NULL OR created_at > sqlc.narg('created_after'))
+  AND (sqlc.narg('updated_before')::timestamptz IS NULL OR updated_at < sqlc.narg('updated_before'))
+  AND (cardinality(@tags::text[]) = 0 OR (
+    SELECT COUNT(DISTINCT t.name)
+    FROM note_tag nt
+    JOIN tag t ON t.id = nt.tag_id
+    WHERE nt.note_id = note.id AND t.name = ANY(@tags::text[])
+  ) >= CASE WHEN @match_all_tags::bool THEN cardinality(@tags::text[]) ELSE 1 END)
+  AND (
+    NOT @has_cursor::bool
+    OR (@sort_by::text = 'title' AND NOT @descending::bool AND (title, id) > (@cursor_text::text, @cursor_id::int))
+    OR (@sort_by::text = 'title' AND @descending::bool AND (title, id) < (@cursor_text::text, @cursor_id::int))
+    OR (@sort_by::text = 'created_at' AND NOT @descending::bool AND (created_at, id) > (@cursor_time::timestamptz, @cursor_id::int))
+    OR (@sort_by::text = 'created_at' AND @descending::bool AND (created_at, id) < (@cursor_time::timestamptz, @cursor_id::int))
+    OR (@sort_by::text = 'updated_at' AND NOT @descending::bool AND (updated_at, id) > (@cursor_time::timestamptz, @cursor_id::int))
+    OR (@sort_by::text = 'updated_at' AND @descending::bool AND (updated_at, id) < (@cursor_time::timestamptz, @cursor_id::int))
+  )
+ORDER BY
+  CASE WHEN @sort_by::text = 'title' AND NOT @descending::bool THEN title END ASC,
+  CASE WHEN @sort_by::text = 'title' AND @descending::bool THEN title END DESC,
+  CASE WHEN @sort_by::text = 'created_at' AND NOT @descending::bool THEN created_at END ASC,
+  CASE WHEN @sort_by::text = 'created_at' AND @descending::bool THEN created_at END DESC,
+  CASE WHEN @sort_by::text = 'updated_at' AND NOT @descending::bool THEN updated_at END ASC,
+  CASE WHEN @sort_by::text = 'updated_at' AND @descending::bool THEN updated_at END DESC,
+  CASE WHEN NOT @descending::bool THEN id END ASC,
+  CASE WHEN @descending::bool THEN id END DESC
+LIMIT @page_size;
 
 -- name: CreateFolder :one
 INSERT INTO folder (user_id, name, description, parent_folder_id)
 VALUES ($1, $2, $3, $4)
-RETURNING id, user_id, name, description, parent_folder_id, created_at;
+RETURNING id, user_id, name, description, parent_folder_id, created_at, deleted_at, version;
 
 -- name: CreateNote :one
-INSERT INTO note (title, body, folder_id)
-VALUES ($1, $2, $3)
-RETURNING id, title, body, folder_id, created_at;
+INSERT INTO note (user_id, title, body, folder_id, body_format, kind)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, user_id, folder_id, title, body, body_format, kind, created_at, updated_at, version;
 
--- name: UpdateFolder :exec
+-- name: UpdateFolder :one
+-- Solo actualiza si la versión sigue siendo la que leyó el cliente; si no,
+-- no devuelve ninguna fila.
 UPDATE folder
-SET name = $2, description = $3, parent_folder_id = $4, user_id = $5
-WHERE id = $1;
+SET name = $3, description = $4, parent_folder_id = $5, version = version + 1
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL AND version = $6
+RETURNING id, user_id, name, description, parent_folder_id, created_at, deleted_at, version;
 
--- name: UpdateNote :exec
+-- name: UpdateNote :one
+-- Solo actualiza si la versión sigue siendo la que leyó el cliente; si no,
+-- no devuelve ninguna fila.
 UPDATE note
-SET title = $2, body = $3, folder_id = $4, updated_at = CURRENT_TIMESTAMP
-WHERE id = $1;
+SET title = $3, body = $4, folder_id = $5, body_format = $7, updated_at = CURRENT_TIMESTAMP, version = version + 1
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL AND version = $6
+RETURNING id, user_id, folder_id, title, body, body_format, kind, created_at, updated_at, version;
+
+-- name: LockFolders :many
+-- Bloquea las carpetas indicadas (siempre en el mismo orden para evitar
+-- deadlocks) y devuelve los IDs que existen y son del usuario.
+SELECT id
+FROM folder
+WHERE user_id = @user_id AND id = ANY(@folder_ids::int[]) AND deleted_at IS NULL
+ORDER BY id
+FOR UPDATE;
+
+-- name: LockFolderTree :exec
+-- Serializa los cambios de padre de las carpetas de un usuario hasta el fin
+-- de la transacción. Bloquear solo la carpeta y su destino no alcanza: dos
+-- movimientos sobre pares distintos pueden cerrar un ciclo entre los dos.
+-- Usa la forma de dos claves, que no se pisa con el lock de migraciones.
+SELECT pg_advisory_xact_lock(1, @user_id::int);
+
+-- name: IsFolderDescendant :one
+-- Indica si candidate_id es folder_id o alguna de sus subcarpetas.
+WITH RECURSIVE subtree AS (
+    SELECT id
+    FROM folder
+    WHERE id = @folder_id
+  UNION
+    SELECT f.id
+    FROM folder f
+    JOIN subtree s ON f.parent_folder_id = s.id
+)
+SELECT EXISTS (
+  SELECT 1 FROM subtree WHERE id = @candidate_id
+)::bool AS is_descendant;
+
+-- name: MoveFolder :execrows
+UPDATE folder
+SET parent_folder_id = $3, version = version + 1
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
 
--- name: DeleteFolder :exec
+-- name: DeleteFolder :execrows
+-- Borrado definitivo: solo aplica a carpetas que ya están en la papelera.
 DELETE FROM folder
-WHERE id = $1;
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL;
 
--- name: DeleteNote :exec
+-- name: DeleteNote :execrows
+-- Borrado definitivo: solo aplica a notas que ya están en la papelera.
 DELETE FROM note
-WHERE id = $1;
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL;
 
 -- name: GetUser :one
 SELECT id, username, email, password, created_at
@@ -68,9 +191,26 @@ FROM users
 WHERE email = $1;
 
 -- name: ListUsers :many
+-- Cada usuario solo se ve a sí mismo; el listado queda paginado como los
+-- demás para no cambiar la forma de la respuesta.
 SELECT id, username, email, created_at
 FROM users
-ORDER BY username;
+WHERE id = @user_id
+  AND (
+    NOT @has_cursor::bool
+    OR (@sort_by::text = 'username' AND NOT @descending::bool AND (username, id) > (@cursor_text::text, @cursor_id::int))
+    OR (@sort_by::text = 'username' AND @descending::bool AND (username, id) < (@cursor_text::text, @cursor_id::int))
+    OR (@sort_by::text = 'created_at' AND NOT @descending::bool AND (created_at, id) > (@cursor_time::timestamptz, @cursor_id::int))
+    OR (@sort_by::text = 'created_at' AND @descending::bool AND (created_at, id) < (@cursor_time::timestamptz, @cursor_id::int))
+  )
+ORDER BY
+  CASE WHEN @sort_by::text = 'username' AND NOT @descending::bool THEN username END ASC,
+  CASE WHEN @sort_by::text = 'username' AND @descending::bool THEN username END DESC,
+  CASE WHEN @sort_by::text = 'created_at' AND NOT @descending::bool THEN created_at END ASC,
+  CASE WHEN @sort_by::text = 'created_at' AND @descending::bool THEN created_at END DESC,
+  CASE WHEN NOT @descending::bool THEN id END ASC,
+  CASE WHEN @descending::bool THEN id END DESC
+LIMIT @page_size;
 
 -- name: CreateUser :one
 INSERT INTO users (username, email, password)
@@ -82,6 +222,11 @@ UPDATE users
 SET username = $2, email = $3, password = $4
 WHERE id = $1;
 
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET password = $2
+WHERE id = $1;
+
 -- name: DeleteUser :exec
 DELETE FROM users
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
@@ -0,0 +1,35 @@
+-- name: SaveNoteRevision :execrows
+-- Guarda el título, el cuerpo y el formato actuales de la nota como
+-- revisión, solo si la actualización que viene los cambia. created_at es la
+-- fecha en que se escribió ese contenido.
+INSERT INTO note_revision (note_id, title, body, body_format, created_at)
+SELECT id, title, body, body_format, COALESCE(updated_at, CURRENT_TIMESTAMP)
+FROM note
+WHERE id = @id AND user_id = @user_id AND deleted_at IS NULL
+  AND (title IS DISTINCT FROM @new_title::text OR body IS DISTINCT FROM sqlc.narg('new_body')::text
+    OR body_format IS DISTINCT FROM @new_body_format::text);
+
+-- name: ListNoteRevisions :many
+SELECT r.id, r.note_id, r.title, r.created_at
+FROM note_revision r
+JOIN note n ON n.id = r.note_id
+WHERE r.note_id = $1 AND n.user_id = $2 AND n.deleted_at IS NULL
+ORDER BY r.id DESC;
+
+-- name: GetNoteRevision :one
+SELECT r.id, r.note_id, r.title, r.body, r.created_at, r.body_format
+FROM note_revision r
+JOIN note n ON n.id = r.note_id
+WHERE r.id = $1 AND r.note_id = $2 AND n.user_id = $3 AND n.deleted_at IS NULL;
+
+-- name: PruneNoteRevisions :execrows
+-- Deja solo las keep revisiones más nuevas de la nota.
+DELETE FROM note_revision
+WHERE note_id = @note_id
+  AND id NOT IN (
+    SELECT id
+    FROM note_revision
+    WHERE note_id = @note_id
+    ORDER BY id DESC
+    LIMIT @keep::int
+  );
@@ -0,0 +1,26 @@
+-- name: SearchNotes :many
+-- Búsqueda full-text sobre título y cuerpo. Si folder_id no es NULL se
+-- restringe a esa carpeta y todas sus subcarpetas.
+WITH RECURSIVE scope AS (
+    SELECT id
+    FROM folder
+    WHERE id = sqlc.narg('folder_id') AND user_id = @user_id AND deleted_at IS NULL
+  UNION
+    SELECT f.id
+    FROM folder f
+    JOIN scope s ON f.parent_folder_id = s.id
+    WHERE f.deleted_at IS NULL
+)
+SELECT n.id, n.folder_id, n.title, n.updated_at,
+  ts_rank_cd(n.search_vector, to_tsquery('simple', @query)) AS rank,
+  ts_headline('simple', n.title, to_tsquery('simple', @query),
+    'HighlightAll=true, StartSel=[[[, StopSel=]]]') AS title_highlight,
+  ts_headline('simple', coalesce(n.body, ''), to_tsquery('simple', @query),
+    'StartSel=[[[, StopSel=]]], MaxFragments=2, MaxWords=20, MinWords=5') AS snippet
+FROM note n
+WHERE n.user_id = @user_id
+  AND n.deleted_at IS NULL
+  AND n.search_vector @@ to_tsquery('simple', @query)
+  AND (sqlc.narg('folder_id')::int IS NULL OR n.folder_id IN (SELECT id FROM scope))
+ORDER BY rank DESC, n.updated_at DESC
+LIMIT @max_results;
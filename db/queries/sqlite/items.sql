@@ -0,0 +1,6 @@
+-- name: LockNote :one
+-- Sin FOR UPDATE: BEGIN IMMEDIATE ya bloquea la base para escritura (ver
+-- LockFolders).
+SELECT kind
+FROM note
+WHERE id = ?1 AND user_id = ?2 AND deleted_at IS NULL;
@@ -0,0 +1,48 @@
+-- name: ListNotes :many
+-- tags llega como un array JSON.
+SELECT id, user_id, folder_id, title, body, body_format, kind, created_at, updated_at, version
+FROM note
+WHERE user_id = ?1
+  AND deleted_at IS NULL
+  AND (?2 IS NULL OR folder_id = ?2)
+  AND (?3 IS NULL OR created_at > ?3)
+  AND (?4 IS NULL OR updated_at < ?4)
+  AND (json_array_length(?5) = 0 OR (
+    SELECT COUNT(DISTINCT t.name)
+    FROM note_tag nt
+    JOIN tag t ON t.id = nt.tag_id
+    WHERE nt.note_id = note.id AND t.name IN (SELECT value FROM json_each(?5))
+  ) >= CASE WHEN ?6 THEN json_array_length(?5) ELSE 1 END)
+  AND (
+    NOT ?7
+    OR (?8 = 'title' AND NOT ?9 AND (title, id) > (?10, ?11))
+    OR (?8 = 'title' AND ?9 AND (title, id) < (?10, ?11))
+    OR (?8 = 'created_at' AND NOT ?9 AND (created_at, id) > (?12, ?11))
+    OR (?8 = 'created_at' AND ?9 AND (created_at, id) < (?12, ?11))
+    OR (?8 = 'updated_at' AND NOT ?9 AND (updated_at, id) > (?12, ?11))
+    OR (?8 = 'updated_at' AND ?9 AND (updated_at, id) < (?12, ?11))
+  )
+ORDER BY
+  CASE WHEN ?8 = 'title' AND NOT ?9 THEN title END ASC,
+  CASE WHEN ?8 = 'title' AND ?9 THEN title END DESC,
+  CASE WHEN ?8 = 'created_at' AND NOT ?9 THEN created_at END ASC,
+  CASE WHEN ?8 = 'created_at' AND ?9 THEN created_at END DESC,
+  CASE WHEN ?8 = 'updated_at' AND NOT ?9 THEN updated_at END ASC,
+  CASE WHEN ?8 = 'updated_at' AND ?9 THEN updated_at END DESC,
+  CASE WHEN NOT ?9 THEN id END ASC,
+  CASE WHEN ?9 THEN id END DESC
+LIMIT ?13;
+
+-- name: LockFolders :many
+-- SQLite no tiene FOR UPDATE: las transacciones empiezan con BEGIN IMMEDIATE
+-- (_txlock=immediate), que ya bloquea la base entera para escritura.
+-- folder_ids llega como un array JSON.
+SELECT id
+FROM folder
+WHERE user_id = ?1 AND id IN (SELECT value FROM json_each(?2)) AND deleted_at IS NULL
+ORDER BY id;
+
+-- name: LockFolderTree :exec
+-- No hace falta un lock aparte: BEGIN IMMEDIATE ya serializa las
+-- transacciones que escriben.
+SELECT ?1;
@@ -0,0 +1,27 @@
+-- name: SearchNotes :many
+-- query llega ya en la sintaxis de FTS5: la traduce store.SQLite.SearchNotes.
+-- bm25 da valores negativos (más chico es mejor), por eso se invierte el
+-- signo; el título pesa más que el cuerpo, como los pesos A y B de
+-- search_vector.
+WITH RECURSIVE scope AS (
+    SELECT id
+    FROM folder
+    WHERE id = ?1 AND user_id = ?2 AND deleted_at IS NULL
+  UNION
+    SELECT f.id
+    FROM folder f
+    JOIN scope s ON f.parent_folder_id = s.id
+    WHERE f.deleted_at IS NULL
+)
+SELECT n.id, n.folder_id, n.title, n.updated_at,
+  -bm25(note_fts, 1.0, 0.4) AS rank,
+  highlight(note_fts, 0, '[[[', ']]]') AS title_highlight,
+  coalesce(snippet(note_fts, 1, '[[[', ']]]', '', 20), '') AS snippet
+FROM note_fts
+JOIN note n ON n.id = note_fts.rowid
+WHERE note_fts MATCH ?3
+  AND n.user_id = ?2
+  AND n.deleted_at IS NULL
+  AND (?1 IS NULL OR n.folder_id IN (SELECT id FROM scope))
+ORDER BY rank DESC, n.updated_at DESC
+LIMIT ?4;
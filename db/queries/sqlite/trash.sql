@@ -0,0 +1,74 @@
+-- name: TrashFolder :execrows
+-- SQLite no permite UPDATE dentro de un WITH, así que son dos sentencias que
+-- corren en la misma transacción. Primero las notas, mientras las carpetas
+-- todavía no están borradas; las filas afectadas son las de la última.
+WITH RECURSIVE subtree AS (
+    SELECT id
+    FROM folder
+    WHERE id = ?1 AND user_id = ?2 AND deleted_at IS NULL AND version = ?3
+  UNION
+    SELECT f.id
+    FROM folder f
+    JOIN subtree s ON f.parent_folder_id = s.id
+    WHERE f.deleted_at IS NULL
+)
+UPDATE note
+SET deleted_at = ?4
+WHERE folder_id IN (SELECT id FROM subtree) AND deleted_at IS NULL;
+
+WITH RECURSIVE subtree AS (
+    SELECT id
+    FROM folder
+    WHERE id = ?1 AND user_id = ?2 AND deleted_at IS NULL AND version = ?3
+  UNION
+    SELECT f.id
+    FROM folder f
+    JOIN subtree s ON f.parent_folder_id = s.id
+    WHERE f.deleted_at IS NULL
+)
+UPDATE folder
+SET deleted_at = ?4
+WHERE id IN (SELECT id FROM subtree);
+
+-- name: RestoreFolder :execrows
+-- Igual que TrashFolder: las notas primero y después las carpetas.
+WITH RECURSIVE target AS (
+    SELECT id, deleted_at
+    FROM folder
+    WHERE id = ?1 AND user_id = ?2 AND deleted_at IS NOT NULL
+), subtree AS (
+    SELECT id
+    FROM target
+  UNION
+    SELECT f.id
+    FROM folder f
+    JOIN subtree s ON f.parent_folder_id = s.id
+    WHERE f.deleted_at = (SELECT deleted_at FROM target)
+)
+UPDATE note
+SET deleted_at = NULL
+WHERE folder_id IN (SELECT id FROM subtree)
+  AND deleted_at = (SELECT deleted_at FROM target);
+
+WITH RECURSIVE target AS (
+    SELECT id, deleted_at
+    FROM folder
+    WHERE id = ?1 AND user_id = ?2 AND deleted_at IS NOT NULL
+), subtree AS (
+    SELECT id
+    FROM target
+  UNION
+    SELECT f.id
+    FROM folder f
+    JOIN subtree s ON f.parent_folder_id = s.id
+    WHERE f.deleted_at = (SELECT deleted_at FROM target)
+)
+UPDATE folder AS f
+SET deleted_at = NULL,
+  parent_folder_id = CASE
+    WHEN f.id = ?1 AND NOT EXISTS (
+      SELECT 1 FROM folder p WHERE p.id = f.parent_folder_id AND p.deleted_at IS NULL
+    ) THEN NULL
+    ELSE f.parent_folder_id
+  END
+WHERE f.id IN (SELECT id FROM subtree);
@@ -0,0 +1,59 @@
+-- name: ListTags :many
+SELECT t.id, t.name, t.created_at, COUNT(n.id)::int AS note_count
+FROM tag t
+LEFT JOIN note_tag nt ON nt.tag_id = t.id
+LEFT JOIN note n ON n.id = nt.note_id AND n.deleted_at IS NULL
+WHERE t.user_id = $1
+GROUP BY t.id
+ORDER BY t.name;
+
+-- name: GetTag :one
+SELECT id, user_id, name, created_at
+FROM tag
+WHERE id = $1 AND user_id = $2;
+
+-- name: CreateTag :one
+INSERT INTO tag (user_id, name)
+VALUES ($1, $2)
+RETURNING id, user_id, name, created_at;
+
+-- name: UpsertTag :one
+-- Devuelve la etiqueta con ese nombre, creándola si no existe.
+INSERT INTO tag (user_id, name)
+VALUES ($1, $2)
+ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING id, user_id, name, created_at;
+
+-- name: RenameTag :one
+UPDATE tag
+SET name = $3
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, name, created_at;
+
+-- name: DeleteTag :execrows
+DELETE FROM tag
+WHERE id = $1 AND user_id = $2;
+
+-- name: MergeNoteTags :exec
+-- Pasa todas las notas de source_id a target_id sin duplicar relaciones.
+INSERT INTO note_tag (note_id, tag_id)
+SELECT note_id, @target_id::int
+FROM note_tag
+WHERE tag_id = @source_id::int
+ON CONFLICT DO NOTHING;
+
+-- name: ListNoteTags :many
+SELECT t.id, t.user_id, t.name, t.created_at
+FROM tag t
+JOIN note_tag nt ON nt.tag_id = t.id
+WHERE nt.note_id = $1
+ORDER BY t.name;
+
+-- name: AttachTag :exec
+INSERT INTO note_tag (note_id, tag_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: DetachTag :execrows
+DELETE FROM note_tag
+WHERE note_id = $1 AND tag_id = $2;
@@ -0,0 +1,95 @@
+-- name: TrashNote :execrows
+UPDATE note
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL AND version = $3;
+
+-- name: TrashFolder :execrows
+-- Manda a la papelera la carpeta, sus subcarpetas y todas sus notas con el
+-- mismo deleted_at, así después se pueden restaurar juntas.
+WITH RECURSIVE subtree AS (
+    SELECT id
+    FROM folder
+    WHERE id = @id AND user_id = @user_id AND deleted_at IS NULL AND version = @version
+  UNION
+    SELECT f.id
+    FROM folder f
+    JOIN subtree s ON f.parent_folder_id = s.id
+    WHERE f.deleted_at IS NULL
+), trashed_notes AS (
+    UPDATE note
+    SET deleted_at = @deleted_at::timestamptz
+    WHERE folder_id IN (SELECT id FROM subtree) AND deleted_at IS NULL
+)
+UPDATE folder
+SET deleted_at = @deleted_at::timestamptz
+WHERE id IN (SELECT id FROM subtree);
+
+-- name: ListTrashedFolders :many
+-- Solo las carpetas borradas directamente, no las que se borraron junto con
+-- su carpeta padre.
+SELECT f.id, f.user_id, f.name, f.description, f.parent_folder_id, f.created_at, f.deleted_at, f.version
+FROM folder f
+LEFT JOIN folder p ON p.id = f.parent_folder_id
+WHERE f.user_id = $1 AND f.deleted_at IS NOT NULL
+  AND (p.id IS NULL OR p.deleted_at IS DISTINCT FROM f.deleted_at)
+ORDER BY f.deleted_at DESC;
+
+-- name: ListTrashedNotes :many
+-- Solo las notas borradas directamente, no las que se borraron junto con
+-- su carpeta.
+SELECT n.id, n.user_id, n.folder_id, n.title, n.body, n.body_format, n.kind, n.created_at, n.updated_at, n.deleted_at, n.version
+FROM note n
+LEFT JOIN folder f ON f.id = n.folder_id
+WHERE n.user_id = $1 AND n.deleted_at IS NOT NULL
+  AND (f.id IS NULL OR f.deleted_at IS DISTINCT FROM n.deleted_at)
+ORDER BY n.deleted_at DESC;
+
+-- name: RestoreNote :execrows
+-- Si la carpeta original ya no está disponible, la nota vuelve a la raíz.
+UPDATE note AS n
+SET deleted_at = NULL,
+  folder_id = CASE
+    WHEN EXISTS (SELECT 1 FROM folder f WHERE f.id = n.folder_id AND f.deleted_at IS NULL) THEN n.folder_id
+    ELSE NULL
+  END
+WHERE n.id = $1 AND n.user_id = $2 AND n.deleted_at IS NOT NULL;
+
+-- name: RestoreFolder :execrows
+-- Restaura la carpeta y todo lo que se borró junto con ella (mismo
+-- deleted_at). Si la carpeta padre sigue en la papelera, vuelve a la raíz.
+WITH RECURSIVE target AS (
+    SELECT id, deleted_at
+    FROM folder
+    WHERE id = @id AND user_id = @user_id AND deleted_at IS NOT NULL
+), subtree AS (
+    SELECT id
+    FROM target
+  UNION
+    SELECT f.id
+    FROM folder f
+    JOIN subtree s ON f.parent_folder_id = s.id
+    WHERE f.deleted_at = (SELECT deleted_at FROM target)
+), restored_notes AS (
+    UPDATE note
+    SET deleted_at = NULL
+    WHERE folder_id IN (SELECT id FROM subtree)
+      AND deleted_at = (SELECT deleted_at FROM target)
+)
+UPDATE folder f
+SET deleted_at = NULL,
+  parent_folder_id = CASE
+    WHEN f.id = @id AND NOT EXISTS (
+      SELECT 1 FROM folder p WHERE p.id = f.parent_folder_id AND p.deleted_at IS NULL
+    ) THEN NULL
+    ELSE f.parent_folder_id
+  END
+WHERE f.id IN (SELECT id FROM subtree);
+
+-- name: PurgeTrashedNotes :execrows
+DELETE FROM note
+WHERE deleted_at < @purge_before::timestamptz;
+
+-- name: PurgeTrashedFolders :execrows
+-- Las subcarpetas y notas se borran por ON DELETE CASCADE.
+DELETE FROM folder
+WHERE deleted_at < @purge_before::timestamptz;
@@ -6,24 +6,71 @@ package db
 
 import (
 	"database/sql"
+	"time"
 )
 
 type Folder struct {
 	ID             int32
-	UserID         sql.NullInt32
+	UserID         int32
 	Name           string
 	Description    sql.NullString
 	ParentFolderID sql.NullInt32
 	CreatedAt      sql.NullTime
+	DeletedAt      sql.NullTime
+	Version        int32
 }
 
 type Note struct {
+	ID           int32
+	FolderID     sql.NullInt32
+	Title        string
+	Body         sql.NullString
+	CreatedAt    sql.NullTime
+	UpdatedAt    sql.NullTime
+	UserID       int32
+	SearchVector interface{}
+	DeletedAt    sql.NullTime
+	Version      int32
+	BodyFormat   string
+	Kind         string
+}
+
+type NoteItem struct {
+	ID       int32
+	NoteID   int32
+	Text     string
+	Checked  bool
+	Position int32
+	Indent   int32
+}
+
+type NoteRevision struct {
+	ID         int32
+	NoteID     int32
+	Title      string
+	Body       sql.NullString
+	CreatedAt  sql.NullTime
+	BodyFormat string
+}
+
+type NoteTag struct {
+	NoteID int32
+	TagID  int32
+}
+
+type Session struct {
+	ID        int32
+	UserID    int32
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type Tag struct {
 	ID        int32
-	FolderID  sql.NullInt32
-	Title     string
-	Body      sql.NullString
+	UserID    int32
+	Name      string
 	CreatedAt sql.NullTime
-	UpdatedAt sql.NullTime
 }
 
 type User struct {
@@ -8,16 +8,41 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
 )
 
+const countNotes = `-- name: CountNotes :one
+SELECT COUNT(*) AS total,
+  COUNT(*) FILTER (WHERE folder_id IS NULL) AS unfiled
+FROM note
+WHERE user_id = $1 AND deleted_at IS NULL
+`
+
+type CountNotesRow struct {
+	Total   int64
+	Unfiled int64
+}
+
+func (q *Queries) CountNotes(ctx context.Context, userID int32) (CountNotesRow, error) {
+	row := q.db.QueryRowContext(ctx, countNotes, userID)
+	var i CountNotesRow
+	err := row.Scan(
+		&i.Total,
+		&i.Unfiled,
+	)
+	return i, err
+}
+
 const createFolder = `-- name: CreateFolder :one
 INSERT INTO folder (user_id, name, description, parent_folder_id)
 VALUES ($1, $2, $3, $4)
-RETURNING id, user_id, name, description, parent_folder_id, created_at
+RETURNING id, user_id, name, description, parent_folder_id, created_at, deleted_at, version
 `
 
 type CreateFolderParams struct {
-	UserID         sql.NullInt32
+	UserID         int32
 	Name           string
 	Description    sql.NullString
 	ParentFolderID sql.NullInt32
@@ -38,39 +63,61 @@ func (q *Queries) CreateFolder(ctx context.Context, arg CreateFolderParams) (Fol
 		&i.Description,
 		&i.ParentFolderID,
 		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Version,
 	)
 	return i, err
 }
 
 const createNote = `-- name: CreateNote :one
-INSERT INTO note (title, body, folder_id)
-VALUES ($1, $2, $3)
-RETURNING id, title, body, folder_id, created_at
+INSERT INTO note (user_id, title, body, folder_id, body_format, kind)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, user_id, folder_id, title, body, body_format, kind, created_at, updated_at, version
 `
 
 type CreateNoteParams struct {
-	Title    string
-	Body     sql.NullString
-	FolderID sql.NullInt32
+	UserID     int32
+	Title      string
+	Body       sql.NullString
+	FolderID   sql.NullInt32
+	BodyFormat string
+	Kind       string
 }
 
 type CreateNoteRow struct {
-	ID        int32
-	Title     string
-	Body      sql.NullString
-	FolderID  sql.NullInt32
-	CreatedAt sql.NullTime
+	ID         int32
+	UserID     int32
+	FolderID   sql.NullInt32
+	Title      string
+	Body       sql.NullString
+	BodyFormat string
+	Kind       string
+	CreatedAt  sql.NullTime
+	UpdatedAt  sql.NullTime
+	Version    int32
 }
 
 func (q *Queries) CreateNote(ctx context.Context, arg CreateNoteParams) (CreateNoteRow, error) {
-	row := q.db.QueryRowContext(ctx, createNote, arg.Title, arg.Body, arg.FolderID)
+	row := q.db.QueryRowContext(ctx, createNote,
+		arg.UserID,
+		arg.Title,
+		arg.Body,
+		arg.FolderID,
+		arg.BodyFormat,
+		arg.Kind,
+	)
 	var i CreateNoteRow
 	err := row.Scan(
 		&i.ID,
+		&i.UserID,
+		&i.FolderID,
 		&i.Title,
 		&i.Body,
-		&i.FolderID,
+		&i.BodyFormat,
+		&i.Kind,
 		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
 	)
 	return i, err
 }
@@ -106,24 +153,42 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 	return i, err
 }
 
-const deleteFolder = `-- name: DeleteFolder :exec
+const deleteFolder = `-- name: DeleteFolder :execrows
 DELETE FROM folder
-WHERE id = $1
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
 `
 
-func (q *Queries) DeleteFolder(ctx context.Context, id int32) error {
-	_, err := q.db.ExecContext(ctx, deleteFolder, id)
-	return err
+type DeleteFolderParams struct {
+	ID     int32
+	UserID int32
+}
+
+// Borrado definitivo: solo aplica a carpetas que ya están en la papelera.
+func (q *Queries) DeleteFolder(ctx context.Context, arg DeleteFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFolder, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-const deleteNote = `-- name: DeleteNote :exec
+const deleteNote = `-- name: DeleteNote :execrows
 DELETE FROM note
-WHERE id = $1
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
 `
 
-func (q *Queries) DeleteNote(ctx context.Context, id int32) error {
-	_, err := q.db.ExecContext(ctx, deleteNote, id)
-	return err
+type DeleteNoteParams struct {
+	ID     int32
+	UserID int32
+}
+
+// Borrado definitivo: solo aplica a notas que ya están en la papelera.
+func (q *Queries) DeleteNote(ctx context.Context, arg DeleteNoteParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteNote, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const deleteUser = `-- name: DeleteUser :exec
@@ -137,13 +202,18 @@ func (q *Queries) DeleteUser(ctx context.Context, id int32) error {
 }
 
 const getFolder = `-- name: GetFolder :one
-SELECT id, user_id, name, description, parent_folder_id, created_at
+SELECT id, user_id, name, description, parent_folder_id, created_at, deleted_at, version
 FROM folder
-WHERE id = $1
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 `
 
-func (q *Queries) GetFolder(ctx context.Context, id int32) (Folder, error) {
-	row := q.db.QueryRowContext(ctx, getFolder, id)
+type GetFolderParams struct {
+	ID     int32
+	UserID int32
+}
+
+func (q *Queries) GetFolder(ctx context.Context, arg GetFolderParams) (Folder, error) {
+	row := q.db.QueryRowContext(ctx, getFolder, arg.ID, arg.UserID)
 	var i Folder
 	err := row.Scan(
 		&i.ID,
@@ -152,26 +222,50 @@ func (q *Queries) GetFolder(ctx context.Context, id int32) (Folder, error) {
 		&i.Description,
 		&i.ParentFolderID,
 		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Version,
 	)
 	return i, err
 }
 
 const getNote = `-- name: GetNote :one
-SELECT id, folder_id, title, body, created_at, updated_at
+SELECT id, user_id, folder_id, title, body, body_format, kind, created_at, updated_at, version
 FROM note
-WHERE id = $1
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 `
 
-func (q *Queries) GetNote(ctx context.Context, id int32) (Note, error) {
-	row := q.db.QueryRowContext(ctx, getNote, id)
-	var i Note
+type GetNoteParams struct {
+	ID     int32
+	UserID int32
+}
+
+type GetNoteRow struct {
+	ID         int32
+	UserID     int32
+	FolderID   sql.NullInt32
+	Title      string
+	Body       sql.NullString
+	BodyFormat string
+	Kind       string
+	CreatedAt  sql.NullTime
+	UpdatedAt  sql.NullTime
+	Version    int32
+}
+
+func (q *Queries) GetNote(ctx context.Context, arg GetNoteParams) (GetNoteRow, error) {
+	row := q.db.QueryRowContext(ctx, getNote, arg.ID, arg.UserID)
+	var i GetNoteRow
 	err := row.Scan(
 		&i.ID,
+		&i.UserID,
 		&i.FolderID,
 		&i.Title,
 		&i.Body,
+		&i.BodyFormat,
+		&i.Kind,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
 	)
 	return i, err
 }
@@ -233,28 +327,89 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User,
 	return i, err
 }
 
-const listFolders = `-- name: ListFolders :many
-SELECT id, user_id, name, description, parent_folder_id, created_at
-FROM folder
-ORDER BY name
+const isFolderDescendant = `-- name: IsFolderDescendant :one
+WITH RECURSIVE subtree AS (
+    SELECT id
+    FROM folder
+    WHERE id = $1
+  UNION
+    SELECT f.id
+    FROM folder f
+    JOIN subtree s ON f.parent_folder_id = s.id
+)
+SELECT EXISTS (
+  SELECT 1 FROM subtree WHERE id = $2
+)::bool AS is_descendant
 `
 
-func (q *Queries) ListFolders(ctx context.Context) ([]Folder, error) {
-	rows, err := q.db.QueryContext(ctx, listFolders)
+type IsFolderDescendantParams struct {
+	FolderID    int32
+	CandidateID int32
+}
+
+// Indica si candidate_id es folder_id o alguna de sus subcarpetas.
+func (q *Queries) IsFolderDescendant(ctx context.Context, arg IsFolderDescendantParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isFolderDescendant, arg.FolderID, arg.CandidateID)
+	var isDescendant bool
+	err := row.Scan(&isDescendant)
+	return isDescendant, err
+}
+
+const listFolderTree = `-- name: ListFolderTree :many
+WITH RECURSIVE subtree AS (
+    SELECT id AS ancestor_id, id AS folder_id
+    FROM folder
+    WHERE user_id = $1 AND deleted_at IS NULL
+  UNION
+    SELECT s.ancestor_id, f.id
+    FROM subtree s
+    JOIN folder f ON f.parent_folder_id = s.folder_id
+    WHERE f.deleted_at IS NULL
+)
+SELECT f.id, f.name, f.description, f.parent_folder_id, f.created_at,
+  (SELECT COUNT(*)
+   FROM note n
+   WHERE n.folder_id = f.id AND n.deleted_at IS NULL)::int AS note_count,
+  (SELECT COUNT(*)
+   FROM subtree s
+   JOIN note n ON n.folder_id = s.folder_id
+   WHERE s.ancestor_id = f.id AND n.deleted_at IS NULL)::int AS total_note_count
+FROM folder f
+WHERE f.user_id = $1 AND f.deleted_at IS NULL
+ORDER BY f.name
+`
+
+type ListFolderTreeRow struct {
+	ID             int32
+	Name           string
+	Description    sql.NullString
+	ParentFolderID sql.NullInt32
+	CreatedAt      sql.NullTime
+	NoteCount      int32
+	TotalNoteCount int32
+}
+
+// Devuelve las carpetas del usuario con la cantidad de notas directas y
+// la cantidad total incluyendo todas las subcarpetas. UNION (y no UNION
+// ALL) descarta los pares repetidos, así que la recursión termina aunque
+// los datos tengan un ciclo.
+func (q *Queries) ListFolderTree(ctx context.Context, userID int32) ([]ListFolderTreeRow, error) {
+	rows, err := q.db.QueryContext(ctx, listFolderTree, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Folder
+	var items []ListFolderTreeRow
 	for rows.Next() {
-		var i Folder
+		var i ListFolderTreeRow
 		if err := rows.Scan(
 			&i.ID,
-			&i.UserID,
 			&i.Name,
 			&i.Description,
 			&i.ParentFolderID,
 			&i.CreatedAt,
+			&i.NoteCount,
+			&i.TotalNoteCount,
 		); err != nil {
 			return nil, err
 		}
@@ -269,15 +424,58 @@ func (q *Queries) ListFolders(ctx context.Context) ([]Folder, error) {
 	return items, nil
 }
 
-const listFoldersByUser = `-- name: ListFoldersByUser :many
-SELECT id, user_id, name, description, parent_folder_id, created_at
+const listFolders = `-- name: ListFolders :many
+SELECT id, user_id, name, description, parent_folder_id, created_at, deleted_at, version
 FROM folder
 WHERE user_id = $1
-ORDER BY name
+  AND deleted_at IS NULL
+  AND ($2::int IS NULL OR parent_folder_id = $2)
+  AND ($3::timestamptz IS NULL OR created_at > $3)
+  AND (
+    NOT $4::bool
+    OR ($5::text = 'name' AND NOT $6::bool AND (name, id) > ($7::text, $8::int))
+    OR ($5::text = 'name' AND $6::bool AND (name, id) < ($7::text, $8::int))
+    OR ($5::text = 'created_at' AND NOT $6::bool AND (created_at, id) > ($9::timestamptz, $8::int))
+    OR ($5::text = 'created_at' AND $6::bool AND (created_at, id) < ($9::timestamptz, $8::int))
+  )
+ORDER BY
+  CASE WHEN $5::text = 'name' AND NOT $6::bool THEN name END ASC,
+  CASE WHEN $5::text = 'name' AND $6::bool THEN name END DESC,
+  CASE WHEN $5::text = 'created_at' AND NOT $6::bool THEN created_at END ASC,
+  CASE WHEN $5::text = 'created_at' AND $6::bool THEN created_at END DESC,
+  CASE WHEN NOT $6::bool THEN id END ASC,
+  CASE WHEN $6::bool THEN id END DESC
+LIMIT $10
 `
 
-func (q *Queries) ListFoldersByUser(ctx context.Context, userID sql.NullInt32) ([]Folder, error) {
-	rows, err := q.db.QueryContext(ctx, listFoldersByUser, userID)
+type ListFoldersParams struct {
+	UserID         int32
+	ParentFolderID sql.NullInt32
+	CreatedAfter   sql.NullTime
+	HasCursor      bool
+	SortBy         string
+	Descending     bool
+	CursorText     string
+	CursorID       int32
+	CursorTime     time.Time
+	PageSize       int32
+}
+
+// Paginación por keyset: el cursor es el valor de la columna de orden y el
+// id de la última fila de la página anterior.
+func (q *Queries) ListFolders(ctx context.Context, arg ListFoldersParams) ([]Folder, error) {
+	rows, err := q.db.QueryContext(ctx, listFolders,
+		arg.UserID,
+		arg.ParentFolderID,
+		arg.CreatedAfter,
+		arg.HasCursor,
+		arg.SortBy,
+		arg.Descending,
+		arg.CursorText,
+		arg.CursorID,
+		arg.CursorTime,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -292,6 +490,8 @@ func (q *Queries) ListFoldersByUser(ctx context.Context, userID sql.NullInt32) (
 			&i.Description,
 			&i.ParentFolderID,
 			&i.CreatedAt,
+			&i.DeletedAt,
+			&i.Version,
 		); err != nil {
 			return nil, err
 		}
@@ -307,27 +507,106 @@ func (q *Queries) ListFoldersByUser(ctx context.Context, userID sql.NullInt32) (
 }
 
 const listNotes = `-- name: ListNotes :many
-SELECT id, folder_id, title, body, created_at, updated_at
+SELECT id, user_id, folder_id, title, body, body_format, kind, created_at, updated_at, version
 FROM note
-ORDER BY title
+WHERE user_id = $1
+  AND deleted_at IS NULL
+  AND ($2::int IS NULL OR folder_id = $2)
+  AND ($3::timestamptz IS NULL OR created_at > $3)
+  AND ($4::timestamptz IS NULL OR updated_at < $4)
+  AND (cardinality($5::text[]) = 0 OR (
+    SELECT COUNT(DISTINCT t.name)
+    FROM note_tag nt
+    JOIN tag t ON t.id = nt.tag_id
+    WHERE nt.note_id = note.id AND t.name = ANY($5::text[])
+  ) >= CASE WHEN $6::bool THEN cardinality($5::text[]) ELSE 1 END)
+  AND (
+    NOT $7::bool
+    OR ($8::text = 'title' AND NOT $9::bool AND (title, id) > ($10::text, $11::int))
+    OR ($8::text = 'title' AND $9::bool AND (title, id) < ($10::text, $11::int))
+    OR ($8::text = 'created_at' AND NOT $9::bool AND (created_at, id) > ($12::timestamptz, $11::int))
+    OR ($8::text = 'created_at' AND $9::bool AND (created_at, id) < ($12::timestamptz, $11::int))
+    OR ($8::text = 'updated_at' AND NOT $9::bool AND (updated_at, id) > ($12::timestamptz, $11::int))
+    OR ($8::text = 'updated_at' AND $9::bool AND (updated_at, id) < ($12::timestamptz, $11::int))
+  )
+ORDER BY
+  CASE WHEN $8::text = 'title' AND NOT $9::bool THEN title END ASC,
+  CASE WHEN $8::text = 'title' AND $9::bool THEN title END DESC,
+  CASE WHEN $8::text = 'created_at' AND NOT $9::bool THEN created_at END ASC,
+  CASE WHEN $8::text = 'created_at' AND $9::bool THEN created_at END DESC,
+  CASE WHEN $8::text = 'updated_at' AND NOT $9::bool THEN updated_at END ASC,
+  CASE WHEN $8::text = 'updated_at' AND $9::bool THEN updated_at END DESC,
+  CASE WHEN NOT $9::bool THEN id END ASC,
+  CASE WHEN $9::bool THEN id END DESC
+LIMIT $13
 `
 
-func (q *Queries) ListNotes(ctx context.Context) ([]Note, error) {
-	rows, err := q.db.QueryContext(ctx, listNotes)
+type ListNotesParams struct {
+	UserID        int32
+	FolderID      sql.NullInt32
+	CreatedAfter  sql.NullTime
+	UpdatedBefore sql.NullTime
+	Tags          []string
+	MatchAllTags  bool
+	HasCursor     bool
+	SortBy        string
+	Descending    bool
+	CursorText    string
+	CursorID      int32
+	CursorTime    time.Time
+	PageSize      int32
+}
+
+type ListNotesRow struct {
+	ID         int32
+	UserID     int32
+	FolderID   sql.NullInt32
+	Title      string
+	Body       sql.NullString
+	BodyFormat string
+	Kind       string
+	CreatedAt  sql.NullTime
+	UpdatedAt  sql.NullTime
+	Version    int32
+}
+
+// Paginación por keyset: el cursor es el valor de la columna de orden y el
+// id de la última fila de la página anterior. Si tags no está vacío, filtra
+// las notas que tienen todas (match_all_tags) o alguna de esas etiquetas.
+func (q *Queries) ListNotes(ctx context.Context, arg ListNotesParams) ([]ListNotesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listNotes,
+		arg.UserID,
+		arg.FolderID,
+		arg.CreatedAfter,
+		arg.UpdatedBefore,
+		pq.Array(arg.Tags),
+		arg.MatchAllTags,
+		arg.HasCursor,
+		arg.SortBy,
+		arg.Descending,
+		arg.CursorText,
+		arg.CursorID,
+		arg.CursorTime,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Note
+	var items []ListNotesRow
 	for rows.Next() {
-		var i Note
+		var i ListNotesRow
 		if err := rows.Scan(
 			&i.ID,
+			&i.UserID,
 			&i.FolderID,
 			&i.Title,
 			&i.Body,
+			&i.BodyFormat,
+			&i.Kind,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Version,
 		); err != nil {
 			return nil, err
 		}
@@ -345,9 +624,35 @@ func (q *Queries) ListNotes(ctx context.Context) ([]Note, error) {
 const listUsers = `-- name: ListUsers :many
 SELECT id, username, email, created_at
 FROM users
-ORDER BY username
+WHERE id = $1
+  AND (
+    NOT $2::bool
+    OR ($3::text = 'username' AND NOT $4::bool AND (username, id) > ($5::text, $6::int))
+    OR ($3::text = 'username' AND $4::bool AND (username, id) < ($5::text, $6::int))
+    OR ($3::text = 'created_at' AND NOT $4::bool AND (created_at, id) > ($7::timestamptz, $6::int))
+    OR ($3::text = 'created_at' AND $4::bool AND (created_at, id) < ($7::timestamptz, $6::int))
+  )
+ORDER BY
+  CASE WHEN $3::text = 'username' AND NOT $4::bool THEN username END ASC,
+  CASE WHEN $3::text = 'username' AND $4::bool THEN username END DESC,
+  CASE WHEN $3::text = 'created_at' AND NOT $4::bool THEN created_at END ASC,
+  CASE WHEN $3::text = 'created_at' AND $4::bool THEN created_at END DESC,
+  CASE WHEN NOT $4::bool THEN id END ASC,
+  CASE WHEN $4::bool THEN id END DESC
+LIMIT $8
 `
 
+type ListUsersParams struct {
+	UserID     int32
+	HasCursor  bool
+	SortBy     string
+	Descending bool
+	CursorText string
+	CursorID   int32
+	CursorTime time.Time
+	PageSize   int32
+}
+
 type ListUsersRow struct {
 	ID        int32
 	Username  string
@@ -355,8 +660,19 @@ type ListUsersRow struct {
 	CreatedAt sql.NullTime
 }
 
-func (q *Queries) ListUsers(ctx context.Context) ([]ListUsersRow, error) {
-	rows, err := q.db.QueryContext(ctx, listUsers)
+// Cada usuario solo se ve a sí mismo; el listado queda paginado como los
+// demás para no cambiar la forma de la respuesta.
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers,
+		arg.UserID,
+		arg.HasCursor,
+		arg.SortBy,
+		arg.Descending,
+		arg.CursorText,
+		arg.CursorID,
+		arg.CursorTime,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -383,52 +699,174 @@ func (q *Queries) ListUsers(ctx context.Context) ([]ListUsersRow, error) {
 	return items, nil
 }
 
-const updateFolder = `-- name: UpdateFolder :exec
+const lockFolderTree = `-- name: LockFolderTree :exec
+SELECT pg_advisory_xact_lock(1, $1::int)
+`
+
+// Serializa los cambios de padre de las carpetas de un usuario hasta el fin
+// de la transacción. Bloquear solo la carpeta y su destino no alcanza: dos
+// movimientos sobre pares distintos pueden cerrar un ciclo entre los dos.
+// Usa la forma de dos claves, que no se pisa con el lock de migraciones.
+func (q *Queries) LockFolderTree(ctx context.Context, userID int32) error {
+	_, err := q.db.ExecContext(ctx, lockFolderTree, userID)
+	return err
+}
+
+const lockFolders = `-- name: LockFolders :many
+SELECT id
+FROM folder
+WHERE user_id = $1 AND id = ANY($2::int[]) AND deleted_at IS NULL
+ORDER BY id
+FOR UPDATE
+`
+
+type LockFoldersParams struct {
+	UserID    int32
+	FolderIds []int32
+}
+
+// Bloquea las carpetas indicadas (siempre en el mismo orden para evitar
+// deadlocks) y devuelve los IDs que existen y son del usuario.
+func (q *Queries) LockFolders(ctx context.Context, arg LockFoldersParams) ([]int32, error) {
+	rows, err := q.db.QueryContext(ctx, lockFolders, arg.UserID, pq.Array(arg.FolderIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveFolder = `-- name: MoveFolder :execrows
 UPDATE folder
-SET name = $2, description = $3, parent_folder_id = $4, user_id = $5
-WHERE id = $1
+SET parent_folder_id = $3, version = version + 1
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+`
+
+type MoveFolderParams struct {
+	ID             int32
+	UserID         int32
+	ParentFolderID sql.NullInt32
+}
+
+func (q *Queries) MoveFolder(ctx context.Context, arg MoveFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveFolder, arg.ID, arg.UserID, arg.ParentFolderID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateFolder = `-- name: UpdateFolder :one
+UPDATE folder
+SET name = $3, description = $4, parent_folder_id = $5, version = version + 1
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL AND version = $6
+RETURNING id, user_id, name, description, parent_folder_id, created_at, deleted_at, version
 `
 
 type UpdateFolderParams struct {
 	ID             int32
+	UserID         int32
 	Name           string
 	Description    sql.NullString
 	ParentFolderID sql.NullInt32
-	UserID         sql.NullInt32
+	Version        int32
 }
 
-func (q *Queries) UpdateFolder(ctx context.Context, arg UpdateFolderParams) error {
-	_, err := q.db.ExecContext(ctx, updateFolder,
+// Solo actualiza si la versión sigue siendo la que leyó el cliente; si no,
+// no devuelve ninguna fila.
+func (q *Queries) UpdateFolder(ctx context.Context, arg UpdateFolderParams) (Folder, error) {
+	row := q.db.QueryRowContext(ctx, updateFolder,
 		arg.ID,
+		arg.UserID,
 		arg.Name,
 		arg.Description,
 		arg.ParentFolderID,
-		arg.UserID,
+		arg.Version,
 	)
-	return err
+	var i Folder
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Description,
+		&i.ParentFolderID,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Version,
+	)
+	return i, err
 }
 
-const updateNote = `-- name: UpdateNote :exec
+const updateNote = `-- name: UpdateNote :one
 UPDATE note
-SET title = $2, body = $3, folder_id = $4, updated_at = CURRENT_TIMESTAMP
-WHERE id = $1
+SET title = $3, body = $4, folder_id = $5, body_format = $7, updated_at = CURRENT_TIMESTAMP, version = version + 1
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL AND version = $6
+RETURNING id, user_id, folder_id, title, body, body_format, kind, created_at, updated_at, version
 `
 
 type UpdateNoteParams struct {
-	ID       int32
-	Title    string
-	Body     sql.NullString
-	FolderID sql.NullInt32
+	ID         int32
+	UserID     int32
+	Title      string
+	Body       sql.NullString
+	FolderID   sql.NullInt32
+	Version    int32
+	BodyFormat string
 }
 
-func (q *Queries) UpdateNote(ctx context.Context, arg UpdateNoteParams) error {
-	_, err := q.db.ExecContext(ctx, updateNote,
+type UpdateNoteRow struct {
+	ID         int32
+	UserID     int32
+	FolderID   sql.NullInt32
+	Title      string
+	Body       sql.NullString
+	BodyFormat string
+	Kind       string
+	CreatedAt  sql.NullTime
+	UpdatedAt  sql.NullTime
+	Version    int32
+}
+
+// Solo actualiza si la versión sigue siendo la que leyó el cliente; si no,
+// no devuelve ninguna fila.
+func (q *Queries) UpdateNote(ctx context.Context, arg UpdateNoteParams) (UpdateNoteRow, error) {
+	row := q.db.QueryRowContext(ctx, updateNote,
 		arg.ID,
+		arg.UserID,
 		arg.Title,
 		arg.Body,
 		arg.FolderID,
+		arg.Version,
+		arg.BodyFormat,
 	)
-	return err
+	var i UpdateNoteRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.FolderID,
+		&i.Title,
+		&i.Body,
+		&i.BodyFormat,
+		&i.Kind,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+	)
+	return i, err
 }
 
 const updateUser = `-- name: UpdateUser :exec
@@ -453,3 +891,19 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) error {
 	)
 	return err
 }
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET password = $2
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID       int32
+	Password string
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.Password)
+	return err
+}
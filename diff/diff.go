@@ -0,0 +1,211 @@
+// Package diff compara textos línea por línea.
+package diff
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// Op indica qué pasó con una línea al pasar del texto viejo al nuevo.
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// Line es una línea del diff.
+type Line struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// MaxLines es la cantidad máxima de líneas, sumando los dos textos, de la
+// parte que cambió (sin el prefijo ni el sufijo comunes). El tiempo crece con
+// el tamaño del texto por la cantidad de cambios, así que sin un tope un par
+// de notas enormes y distintas alcanzan para ocupar el servidor.
+const MaxLines = 10_000
+
+// ErrTooLarge es el error de Lines cuando la parte que cambió supera MaxLines.
+var ErrTooLarge = errors.New("los textos difieren en demasiadas líneas para compararlos")
+
+// Lines devuelve el diff entre old y new usando la subsecuencia común más
+// larga de sus líneas. En cada cambio las líneas borradas van antes que las
+// insertadas.
+func Lines(old, new string) ([]Line, error) {
+	a, b := splitLines(old), splitLines(new)
+	prefix, suffix := commonEnds(a, b)
+	if len(a)+len(b)-2*(prefix+suffix) > MaxLines {
+		return nil, ErrTooLarge
+	}
+	return deletesFirst(myers(a, b, make([]Line, 0, len(a)+len(b)))), nil
+}
+
+// commonEnds devuelve cuántas líneas tienen en común a y b al principio y al
+// final, sin que se solapen. En una nota editada suelen ser casi todo el
+// texto.
+func commonEnds(a, b []string) (prefix, suffix int) {
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	return prefix, suffix
+}
+
+// myers agrega a result el diff de a y b con el algoritmo de Myers en
+// espacio lineal: separa el prefijo y el sufijo comunes, parte lo que queda
+// en el medio del camino de edición más corto y resuelve cada mitad.
+func myers(a, b []string, result []Line) []Line {
+	prefix, suffix := commonEnds(a, b)
+	for _, text := range a[:prefix] {
+		result = append(result, Line{Op: Equal, Text: text})
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	switch {
+	case len(midA) == 0:
+		for _, text := range midB {
+			result = append(result, Line{Op: Insert, Text: text})
+		}
+	case len(midB) == 0:
+		for _, text := range midA {
+			result = append(result, Line{Op: Delete, Text: text})
+		}
+	default:
+		// Sin prefijo ni sufijo comunes hay al menos dos cambios, así que
+		// el punto de corte deja dos mitades más chicas que el total
+		x, y := split(midA, midB)
+		result = myers(midA[:x], midB[:y], result)
+		result = myers(midA[x:], midB[y:], result)
+	}
+
+	for _, text := range a[len(a)-suffix:] {
+		result = append(result, Line{Op: Equal, Text: text})
+	}
+	return result
+}
+
+// split busca el camino de edición más corto de a a b avanzando desde el
+// principio y desde el final a la vez, y devuelve el punto (x, y) donde se
+// encuentran. Solo guarda el avance de cada diagonal, así que usa memoria
+// proporcional a len(a)+len(b).
+func split(a, b []string) (int, int) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+	// forward[offset+k] es hasta qué x llega el camino sobre la diagonal
+	// k = x-y desde el principio; backward lo mismo desde el final, con las
+	// dos secuencias dadas vuelta. -1 es una diagonal todavía sin visitar.
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	delta := n - m
+	// Si delta es impar los caminos se cruzan en el paso hacia adelante
+	front := delta%2 != 0
+	// Diagonales que ya se salieron de la grilla y no hace falta recorrer
+	var fStart, fEnd, bStart, bEnd int
+
+	for d := 0; d < maxD; d++ {
+		for k := -d + fStart; k <= d-fEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case front:
+				if i := offset + delta - k; i >= 0 && i < len(backward) && backward[i] != -1 && x >= n-backward[i] {
+					return x, y
+				}
+			}
+		}
+
+		for k := -d + bStart; k <= d-bEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !front:
+				i := offset + delta - k
+				if i < 0 || i >= len(forward) || forward[i] == -1 {
+					continue
+				}
+				if fx, fy := forward[i], forward[i]-(delta-k); fx >= n-x && fx <= n && fy <= m {
+					return fx, fy
+				}
+			}
+		}
+	}
+	// No pasa: con maxD pasos desde cada punta los caminos siempre se cruzan
+	return n, 0
+}
+
+// deletesFirst ordena cada tramo de cambios para que las líneas borradas
+// queden antes que las insertadas, sin cambiar el orden entre ellas.
+func deletesFirst(lines []Line) []Line {
+	for start := 0; start < len(lines); {
+		if lines[start].Op == Equal {
+			start++
+			continue
+		}
+		end := start
+		for end < len(lines) && lines[end].Op != Equal {
+			end++
+		}
+		slices.SortStableFunc(lines[start:end], func(x, y Line) int {
+			return cmp.Compare(opOrder(x.Op), opOrder(y.Op))
+		})
+		start = end
+	}
+	return lines
+}
+
+func opOrder(op Op) int {
+	if op == Delete {
+		return 0
+	}
+	return 1
+}
+
+// splitLines separa el texto en líneas. Un texto vacío no tiene líneas y un
+// salto de línea final no agrega una línea vacía.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
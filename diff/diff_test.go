@@ -0,0 +1,127 @@
+package diff
+
+import (
+	"errors"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     []Line
+	}{
+		{"vacíos", "", "", []Line{}},
+		{"todo nuevo", "", "a\nb\n", []Line{{Insert, "a"}, {Insert, "b"}}},
+		{"todo borrado", "a\nb", "", []Line{{Delete, "a"}, {Delete, "b"}}},
+		{"iguales", "a\nb\n", "a\r\nb", []Line{{Equal, "a"}, {Equal, "b"}}},
+		{"inserción", "a\nc", "a\nb\nc", []Line{{Equal, "a"}, {Insert, "b"}, {Equal, "c"}}},
+		{"borrado", "a\nb\nc", "a\nc", []Line{{Equal, "a"}, {Delete, "b"}, {Equal, "c"}}},
+		{"reemplazo", "a\nb\nc", "a\nx\ny\nc", []Line{{Equal, "a"}, {Delete, "b"}, {Insert, "x"}, {Insert, "y"}, {Equal, "c"}}},
+		{"sin nada en común", "a\nb", "c\nd", []Line{{Delete, "a"}, {Delete, "b"}, {Insert, "c"}, {Insert, "d"}}},
+		{"cambios separados", "a\nb\nc\nd\ne", "x\nb\nc\ne\ny", []Line{
+			{Delete, "a"}, {Insert, "x"}, {Equal, "b"}, {Equal, "c"}, {Delete, "d"}, {Equal, "e"}, {Insert, "y"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Lines(tt.old, tt.new)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Lines(%q, %q) = %v, quería %v", tt.old, tt.new, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLinesRandom compara contra la tabla completa de la subsecuencia común
+// más larga: el diff tiene que reconstruir los dos textos y no puede tener
+// más cambios que los necesarios.
+func TestLinesRandom(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 500 {
+		a, b := randomLines(r), randomLines(r)
+		got, err := Lines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var old, new []string
+		equal := 0
+		for _, line := range got {
+			if line.Op != Insert {
+				old = append(old, line.Text)
+			}
+			if line.Op != Delete {
+				new = append(new, line.Text)
+			}
+			if line.Op == Equal {
+				equal++
+			}
+		}
+		if !slices.Equal(old, a) || !slices.Equal(new, b) {
+			t.Fatalf("Lines(%q, %q) = %v no reconstruye los textos", a, b, got)
+		}
+		if want := lcsLength(a, b); equal != want {
+			t.Fatalf("Lines(%q, %q) = %v tiene %d líneas iguales, quería %d", a, b, got, equal, want)
+		}
+	}
+}
+
+func TestLinesTooLarge(t *testing.T) {
+	var a, b strings.Builder
+	for i := range MaxLines/2 + 1 {
+		a.WriteString("a\n")
+		if i%2 == 0 {
+			b.WriteString("b\n")
+		} else {
+			b.WriteString("a\n")
+		}
+	}
+	if _, err := Lines(a.String(), b.String()); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("err = %v, quería ErrTooLarge", err)
+	}
+
+	// El prefijo y el sufijo comunes no cuentan para el tope
+	same := strings.Repeat("x\n", MaxLines)
+	got, err := Lines(same+"a\n"+same, same+"b\n"+same)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2*MaxLines+2 {
+		t.Fatalf("len(Lines) = %d", len(got))
+	}
+}
+
+// randomLines arma textos con pocas líneas distintas, para que haya muchas
+// coincidencias.
+func randomLines(r *rand.Rand) []string {
+	lines := make([]string, r.IntN(40))
+	for i := range lines {
+		lines[i] = string(rune('a' + r.IntN(4)))
+	}
+	return lines
+}
+
+func lcsLength(a, b []string) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+	return table[0][0]
+}
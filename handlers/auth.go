@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	sqlc "tpeweb.com/servidor-go/db/sqlc"
+)
+
+const (
+	sessionCookieName = "keepnotes_session"
+	sessionTTL        = 7 * 24 * time.Hour
+)
+
+type contextKey int
+
+const (
+	currentUserKey contextKey = iota
+	requestIDKey
+)
+
+// CurrentUser es el usuario autenticado que RequireAuth deja en el contexto.
+type CurrentUser struct {
+	ID       int32
+	Username string
+	Email    string
+}
+
+// UserFromContext devuelve el usuario autenticado de la request, si lo hay.
+func UserFromContext(ctx context.Context) (CurrentUser, bool) {
+	user, ok := ctx.Value(currentUserKey).(CurrentUser)
+	return user, ok
+}
+
+// currentUserID devuelve el ID del usuario autenticado (0 si no hay sesión).
+func currentUserID(r *http.Request) int32 {
+	user, _ := UserFromContext(r.Context())
+	return user.ID
+}
+
+// RequireAuth valida la cookie de sesión y agrega el usuario al contexto.
+// Responde 401 si la cookie falta, no existe o está vencida.
+func (h *UserHandler) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(sessionCookieName)
+		if err != nil || cookie.Value == "" {
+			writeProblem(w, r, http.StatusUnauthorized, codeUnauthenticated, "No autenticado")
+			return
+		}
+
+		row, err := h.store.GetSessionUser(r.Context(), hashSessionToken(cookie.Value))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				h.clearSessionCookie(w)
+				writeProblem(w, r, http.StatusUnauthorized, codeUnauthenticated, "Sesión inválida o vencida")
+				return
+			}
+			writeInternalError(w, r, err, "Error interno")
+			return
+		}
+
+		user := CurrentUser{ID: row.ID, Username: row.Username, Email: row.Email}
+		ctx := context.WithValue(r.Context(), currentUserKey, user)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// startSession crea una sesión nueva para el usuario y setea la cookie.
+func (h *UserHandler) startSession(w http.ResponseWriter, r *http.Request, userID int32) error {
+	// Limpieza oportunista de sesiones vencidas
+	if err := h.store.DeleteExpiredSessions(r.Context()); err != nil {
+		slog.Error("No se pudieron borrar las sesiones vencidas", "request_id", requestIDFrom(r.Context()), "err", err)
+	}
+
+	token, err := newSessionToken()
+	if err != nil {
+		return err
+	}
+
+	session, err := h.store.CreateSession(r.Context(), sqlc.CreateSessionParams{
+		UserID:    userID,
+		TokenHash: hashSessionToken(token),
+		ExpiresAt: time.Now().Add(sessionTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		MaxAge:   int(sessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   h.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// LogoutHandler revoca la sesión actual y borra la cookie.
+func (h *UserHandler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+
+	cookie, err := r.Cookie(sessionCookieName)
+	if err == nil && cookie.Value != "" {
+		err = h.store.DeleteSession(r.Context(), hashSessionToken(cookie.Value))
+		if err != nil {
+			writeInternalError(w, r, err, "Error al cerrar sesión")
+			return
+		}
+	}
+
+	h.clearSessionCookie(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) clearSessionCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   h.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// newSessionToken genera 32 bytes aleatorios codificados en base64 URL-safe.
+func newSessionToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// En la base solo se guarda el hash del token, nunca el token en sí.
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
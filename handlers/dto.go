@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"database/sql"
+	"time"
+
+	sqlc "tpeweb.com/servidor-go/db/sqlc"
+)
+
+// Representación JSON pública de los recursos. Los handlers nunca codifican
+// los structs de sqlc directamente: los campos van en snake_case, los NULL
+// de la base salen como null y las fechas en RFC 3339.
+
+type noteDTO struct {
+	ID         int32      `json:"id"`
+	FolderID   *int32     `json:"folder_id"`
+	Title      string     `json:"title"`
+	Body       *string    `json:"body"`
+	BodyFormat string     `json:"body_format"`
+	Kind       string     `json:"kind"`
+	CreatedAt  *time.Time `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at"`
+	Version    int32      `json:"version"`
+	// DeletedAt solo aparece en las notas de la papelera
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// noteWithTagsDTO es la respuesta de GET /api/v1/notes/{id}. Items está
+// vacío salvo en las checklists.
+type noteWithTagsDTO struct {
+	noteDTO
+	Tags  []tagDTO      `json:"tags"`
+	Items []noteItemDTO `json:"items"`
+}
+
+type noteItemDTO struct {
+	ID       int32  `json:"id"`
+	NoteID   int32  `json:"note_id"`
+	Text     string `json:"text"`
+	Checked  bool   `json:"checked"`
+	Position int32  `json:"position"`
+	Indent   int32  `json:"indent"`
+}
+
+type folderDTO struct {
+	ID             int32      `json:"id"`
+	Name           string     `json:"name"`
+	Description    *string    `json:"description"`
+	ParentFolderID *int32     `json:"parent_folder_id"`
+	CreatedAt      *time.Time `json:"created_at"`
+	Version        int32      `json:"version"`
+	// DeletedAt solo aparece en las carpetas de la papelera
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type userDTO struct {
+	ID        int32      `json:"id"`
+	Username  string     `json:"username"`
+	Email     string     `json:"email"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+type tagDTO struct {
+	ID        int32      `json:"id"`
+	Name      string     `json:"name"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+// tagWithCountDTO es cada elemento de GET /api/v1/tags.
+type tagWithCountDTO struct {
+	tagDTO
+	NoteCount int32 `json:"note_count"`
+}
+
+type revisionDTO struct {
+	ID        int32      `json:"id"`
+	NoteID    int32      `json:"note_id"`
+	Title     string     `json:"title"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+// revisionDetailDTO es una revisión con su cuerpo.
+type revisionDetailDTO struct {
+	revisionDTO
+	Body       *string `json:"body"`
+	BodyFormat string  `json:"body_format"`
+}
+
+// newNoteDTO recibe GetNoteRow; las filas de ListNotes, CreateNote y
+// UpdateNote tienen las mismas columnas y se convierten con sqlc.GetNoteRow(row).
+func newNoteDTO(n sqlc.GetNoteRow) noteDTO {
+	return noteDTO{
+		ID:         n.ID,
+		FolderID:   nullInt32(n.FolderID),
+		Title:      n.Title,
+		Body:       nullString(n.Body),
+		BodyFormat: n.BodyFormat,
+		Kind:       n.Kind,
+		CreatedAt:  nullTime(n.CreatedAt),
+		UpdatedAt:  nullTime(n.UpdatedAt),
+		Version:    n.Version,
+	}
+}
+
+func newTrashedNoteDTO(n sqlc.ListTrashedNotesRow) noteDTO {
+	note := newNoteDTO(sqlc.GetNoteRow{
+		ID:         n.ID,
+		UserID:     n.UserID,
+		FolderID:   n.FolderID,
+		Title:      n.Title,
+		Body:       n.Body,
+		BodyFormat: n.BodyFormat,
+		Kind:       n.Kind,
+		CreatedAt:  n.CreatedAt,
+		UpdatedAt:  n.UpdatedAt,
+		Version:    n.Version,
+	})
+	note.DeletedAt = nullTime(n.DeletedAt)
+	return note
+}
+
+func newNoteItemDTO(i sqlc.NoteItem) noteItemDTO {
+	return noteItemDTO{
+		ID:       i.ID,
+		NoteID:   i.NoteID,
+		Text:     i.Text,
+		Checked:  i.Checked,
+		Position: i.Position,
+		Indent:   i.Indent,
+	}
+}
+
+func newFolderDTO(f sqlc.Folder) folderDTO {
+	return folderDTO{
+		ID:             f.ID,
+		Name:           f.Name,
+		Description:    nullString(f.Description),
+		ParentFolderID: nullInt32(f.ParentFolderID),
+		CreatedAt:      nullTime(f.CreatedAt),
+		Version:        f.Version,
+		DeletedAt:      nullTime(f.DeletedAt),
+	}
+}
+
+// newUserDTO recibe ListUsersRow, que no trae la contraseña; CreateUserRow
+// tiene las mismas columnas y se convierte con sqlc.ListUsersRow(row).
+func newUserDTO(u sqlc.ListUsersRow) userDTO {
+	return userDTO{
+		ID:        u.ID,
+		Username:  u.Username,
+		Email:     u.Email,
+		CreatedAt: nullTime(u.CreatedAt),
+	}
+}
+
+func newTagDTO(t sqlc.Tag) tagDTO {
+	return tagDTO{
+		ID:        t.ID,
+		Name:      t.Name,
+		CreatedAt: nullTime(t.CreatedAt),
+	}
+}
+
+func newTagWithCountDTO(t sqlc.ListTagsRow) tagWithCountDTO {
+	return tagWithCountDTO{
+		tagDTO:    tagDTO{ID: t.ID, Name: t.Name, CreatedAt: nullTime(t.CreatedAt)},
+		NoteCount: t.NoteCount,
+	}
+}
+
+func newRevisionDTO(r sqlc.ListNoteRevisionsRow) revisionDTO {
+	return revisionDTO{
+		ID:        r.ID,
+		NoteID:    r.NoteID,
+		Title:     r.Title,
+		CreatedAt: nullTime(r.CreatedAt),
+	}
+}
+
+func newRevisionDetailDTO(r sqlc.NoteRevision) revisionDetailDTO {
+	return revisionDetailDTO{
+		revisionDTO: revisionDTO{
+			ID:        r.ID,
+			NoteID:    r.NoteID,
+			Title:     r.Title,
+			CreatedAt: nullTime(r.CreatedAt),
+		},
+		Body:       nullString(r.Body),
+		BodyFormat: r.BodyFormat,
+	}
+}
+
+// mapSlice convierte cada fila con f. Nunca devuelve nil, así una lista
+// vacía sale como [] y no como null.
+func mapSlice[T, U any](rows []T, f func(T) U) []U {
+	result := make([]U, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, f(row))
+	}
+	return result
+}
+
+// mapPage convierte los elementos de una página sin tocar el cursor.
+func mapPage[T, U any](p page[T], f func(T) U) page[U] {
+	return page[U]{Items: mapSlice(p.Items, f), NextCursor: p.NextCursor}
+}
+
+func nullInt32(v sql.NullInt32) *int32 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int32
+}
+
+func nullString(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	return &v.String
+}
+
+func nullTime(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Time
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// errStaleVersion indica que la fila cambió desde que el cliente la leyó.
+var errStaleVersion = errors.New("la versión no coincide")
+
+// etag arma el ETag de una nota o carpeta a partir de su columna version.
+func etag(version int32) string {
+	return `"` + strconv.Itoa(int(version)) + `"`
+}
+
+// precondition es lo que el cliente mandó en If-Match: una versión concreta
+// o *, que acepta cualquiera mientras el recurso exista.
+type precondition struct {
+	version int32
+	any     bool
+}
+
+// matches dice si la versión actual del recurso cumple la precondición.
+func (p precondition) matches(current int32) bool {
+	return p.any || p.version == current
+}
+
+// ifMatchVersion lee el If-Match. Responde 428 si falta el header, 400 si
+// trae un ETag débil (W/"3"), que If-Match nunca acepta porque compara en
+// forma estricta, y 412 si no es un ETag de los que devolvemos.
+func ifMatchVersion(w http.ResponseWriter, r *http.Request) (precondition, bool) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" {
+		writeProblem(w, r, http.StatusPreconditionRequired, codeIfMatchRequired, "Falta el header If-Match con el ETag del recurso")
+		return precondition{}, false
+	}
+	if value == "*" {
+		return precondition{any: true}, true
+	}
+	if strings.HasPrefix(value, "W/") {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidRequest, "If-Match no acepta ETags débiles; mandá el ETag tal como vino en la respuesta")
+		return precondition{}, false
+	}
+	version, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 32)
+	if err != nil || !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) {
+		writeProblem(w, r, http.StatusPreconditionFailed, codeVersionMismatch, "If-Match no coincide con la versión actual")
+		return precondition{}, false
+	}
+	return precondition{version: int32(version)}, true
+}
+
+// writeStale responde 412 con la copia actual del servidor y su ETag, para
+// que el cliente pueda mezclar los cambios y reintentar.
+func writeStale(w http.ResponseWriter, version int32, current any) {
+	w.Header().Set("ETag", etag(version))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusPreconditionFailed)
+	json.NewEncoder(w).Encode(current)
+}
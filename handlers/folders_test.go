@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func createFolder(c *client, name string, parentID *int32) folderDTO {
+	c.t.Helper()
+	rec := c.do("POST", "/api/v1/folders", map[string]any{"name": name, "parent_folder_id": parentID})
+	expectStatus(c.t, rec, http.StatusCreated)
+	var folder folderDTO
+	decode(c.t, rec, &folder)
+	return folder
+}
+
+func TestFolderTrashCascade(t *testing.T) {
+	h, _ := newTestAPI(t)
+	c := newClient(t, h)
+	c.signUp("ana")
+
+	parent := createFolder(c, "Trabajo", nil)
+	child := createFolder(c, "Reuniones", &parent.ID)
+	note := createNote(c, map[string]any{"title": "Acta", "folder_id": child.ID})
+	parentPath := path("/api/v1/folders", parent.ID)
+
+	var tree struct {
+		Folders []struct {
+			ID             int32 `json:"id"`
+			TotalNoteCount int32 `json:"total_note_count"`
+		} `json:"folders"`
+	}
+	rec := c.do("GET", "/api/v1/folders/tree", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &tree)
+	if len(tree.Folders) != 1 || tree.Folders[0].TotalNoteCount != 1 {
+		t.Fatalf("árbol = %+v", tree)
+	}
+
+	rec = c.do("DELETE", parentPath, nil, "If-Match", `"1"`)
+	expectStatus(t, rec, http.StatusNoContent)
+	for _, p := range []string{parentPath, path("/api/v1/folders", child.ID), path("/api/v1/notes", note.ID)} {
+		rec = c.do("GET", p, nil)
+		expectProblem(t, rec, http.StatusNotFound, codeNotFound)
+	}
+
+	// En la papelera aparece solo lo que se borró directamente
+	var trash trashResponse
+	rec = c.do("GET", "/api/v1/trash", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &trash)
+	if len(trash.Folders) != 1 || trash.Folders[0].ID != parent.ID || len(trash.Notes) != 0 {
+		t.Fatalf("papelera = %+v", trash)
+	}
+
+	rec = c.do("POST", path("/api/v1/trash/folders", parent.ID)+"/restore", nil)
+	expectStatus(t, rec, http.StatusOK)
+	rec = c.do("GET", path("/api/v1/notes", note.ID), nil)
+	expectStatus(t, rec, http.StatusOK)
+
+	// El borrado definitivo se lleva las subcarpetas y las notas
+	rec = c.do("DELETE", parentPath, nil, "If-Match", `"1"`)
+	expectStatus(t, rec, http.StatusNoContent)
+	rec = c.do("DELETE", path("/api/v1/trash/folders", parent.ID), nil)
+	expectStatus(t, rec, http.StatusNoContent)
+	for _, p := range []string{path("/api/v1/trash/folders", child.ID), path("/api/v1/trash/notes", note.ID)} {
+		rec = c.do("POST", p+"/restore", nil)
+		expectProblem(t, rec, http.StatusNotFound, codeNotFound)
+	}
+}
+
+func TestMoveFolder(t *testing.T) {
+	h, _ := newTestAPI(t)
+	c := newClient(t, h)
+	c.signUp("ana")
+
+	parent := createFolder(c, "A", nil)
+	child := createFolder(c, "B", &parent.ID)
+	other := createFolder(c, "C", nil)
+
+	rec := c.do("POST", path("/api/v1/folders", parent.ID)+"/move", map[string]any{"parent_folder_id": child.ID})
+	expectProblem(t, rec, http.StatusConflict, codeFolderCycle)
+	rec = c.do("POST", path("/api/v1/folders", parent.ID)+"/move", map[string]any{"parent_folder_id": parent.ID})
+	expectProblem(t, rec, http.StatusConflict, codeFolderCycle)
+	rec = c.do("POST", path("/api/v1/folders", parent.ID)+"/move", map[string]any{"parent_folder_id": 99})
+	expectProblem(t, rec, http.StatusNotFound, codeNotFound)
+
+	rec = c.do("POST", path("/api/v1/folders", child.ID)+"/move", map[string]any{"parent_folder_id": other.ID})
+	expectStatus(t, rec, http.StatusOK)
+	var moved folderDTO
+	decode(t, rec, &moved)
+	if moved.ParentFolderID == nil || *moved.ParentFolderID != other.ID || moved.Version != 2 {
+		t.Fatalf("carpeta movida = %+v", moved)
+	}
+	if got := rec.Header().Get("ETag"); got != `"2"` {
+		t.Fatalf("ETag = %s", got)
+	}
+}
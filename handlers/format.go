@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	sqlc "tpeweb.com/servidor-go/db/sqlc"
+	"tpeweb.com/servidor-go/render"
+)
+
+var errInvalidBodyFormat = fieldError{"body_format", "body_format tiene que ser plain o markdown"}
+
+// parseBodyFormat valida el body_format de un create o un PUT; si no viene,
+// la nota es de texto plano.
+func parseBodyFormat(format *string) (string, error) {
+	if format == nil {
+		return render.Plain, nil
+	}
+	if !render.Valid(*format) {
+		return "", errInvalidBodyFormat
+	}
+	return *format, nil
+}
+
+// writeNoteHTML responde el cuerpo de la nota como HTML sanitizado, con el
+// mismo ETag que la nota en JSON.
+func (h *UserHandler) writeNoteHTML(w http.ResponseWriter, r *http.Request, note sqlc.GetNoteRow) {
+	body, err := render.HTML(note.BodyFormat, note.Body.String)
+	if err != nil {
+		writeInternalError(w, r, err, "Error al convertir la nota a HTML")
+		return
+	}
+	w.Header().Set("ETag", etag(note.Version))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(body))
+}
@@ -1,82 +1,135 @@
 package handlers
 
 import (
-	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
-	"log"
+	"log/slog"
 	"net/http"
-	"strconv"
+	"time"
 
+	"tpeweb.com/servidor-go/checklist"
 	sqlc "tpeweb.com/servidor-go/db/sqlc"
+	"tpeweb.com/servidor-go/password"
+	"tpeweb.com/servidor-go/render"
+	"tpeweb.com/servidor-go/store"
 )
 
 type UserHandler struct {
-	queries *sqlc.Queries
+	store store.Store
+
+	// MaxRevisions es la cantidad de revisiones que se guardan por nota;
+	// con 0 no se borran nunca.
+	MaxRevisions int
+
+	// CookieSecure marca la cookie de sesión como Secure. NewUserHandler lo
+	// deja en true.
+	CookieSecure bool
 }
 
-func NewUserHandler(q *sqlc.Queries) *UserHandler {
-	return &UserHandler{queries: q}
+func NewUserHandler(s store.Store) *UserHandler {
+	return &UserHandler{store: s, CookieSecure: true}
 }
 
-func (h *UserHandler) NotesHandler(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case "GET":
-		h.getNotes(w, r)
-	case "POST":
-		h.createNote(w, r)
-	default:
-		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+// getNotes lista las notas paginadas. Acepta limit, cursor, sort (title,
+// updated_at o created_at), order (asc o desc) y los filtros folder_id,
+// created_after, updated_before y tag (repetible, con tag_match=all|any).
+func (h *UserHandler) getNotes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	req, err := parsePageRequest(r, "title", "updated_at", "created_at")
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
 	}
-}
-func (h *UserHandler) NoteHandler(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case "GET":
-		h.getNoteByID(w, r)
-	case "PUT":
-		h.updateNote(w, r)
-	case "DELETE":
-		h.deleteNote(w, r)
-	default:
-		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+	cursorTime, err := req.cursorTime()
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
 	}
-}
 
-func (h *UserHandler) getNotes(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
-	notes, err := h.queries.ListNotes(ctx)
+	params := sqlc.ListNotesParams{
+		UserID:     currentUserID(r),
+		HasCursor:  req.Cursor != nil,
+		SortBy:     req.SortBy,
+		Descending: req.Descending,
+		CursorText: req.cursorText(),
+		CursorID:   req.cursorID(),
+		CursorTime: cursorTime,
+		PageSize:   req.fetchSize(),
+	}
+	if params.FolderID, err = parseIDFilter(r, "folder_id"); err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	if params.CreatedAfter, err = parseTimeFilter(r, "created_after"); err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	if params.UpdatedBefore, err = parseTimeFilter(r, "updated_before"); err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	if params.Tags, params.MatchAllTags, err = parseTagFilter(r); err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	notes, err := h.store.ListNotes(ctx, params)
 	if err != nil {
-		http.Error(w, "Error al listar notas: "+err.Error(), http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error al listar notas")
 		return
 	}
+	result := newPage(req, notes, func(n sqlc.ListNotesRow) (string, int32) {
+		switch req.SortBy {
+		case "updated_at":
+			return formatCursorTime(n.UpdatedAt), n.ID
+		case "created_at":
+			return formatCursorTime(n.CreatedAt), n.ID
+		default:
+			return n.Title, n.ID
+		}
+	})
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(notes)
+	json.NewEncoder(w).Encode(mapPage(result, func(n sqlc.ListNotesRow) noteDTO {
+		return newNoteDTO(sqlc.GetNoteRow(n))
+	}))
 }
 
 func (h *UserHandler) createNote(w http.ResponseWriter, r *http.Request) {
 
-	ctx := context.Background()
+	ctx := r.Context()
 
 	var note struct {
-		Title    string  `json:"title"`
-		Body     *string `json:"body"`      // puntero para distinguir NULL
-		FolderID *int32  `json:"folder_id"` // puntero para distinguir NULL
+		Title      string  `json:"title"`
+		Body       *string `json:"body"`        // puntero para distinguir NULL
+		BodyFormat *string `json:"body_format"` // plain si no viene
+		Kind       *string `json:"kind"`        // text si no viene
+		FolderID   *int32  `json:"folder_id"`   // puntero para distinguir NULL
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&note); err != nil {
-		http.Error(w, "Error al decodificar JSON: "+err.Error(), http.StatusBadRequest)
+		writeInvalidJSON(w, r)
 		return
 	}
 
 	if note.Title == "" {
-		http.Error(w, "El título es obligatorio", http.StatusBadRequest)
+		writeValidationError(w, r, fieldError{"title", "El título es obligatorio"})
+		return
+	}
+
+	bodyFormat, formatErr := parseBodyFormat(note.BodyFormat)
+	kind, kindErr := parseKind(note.Kind)
+	if err := errors.Join(formatErr, kindErr); err != nil {
+		writeBadRequest(w, r, err)
 		return
 	}
 
 	// Preparar parámetros para sqlc
 	params := sqlc.CreateNoteParams{
-		Title: note.Title,
+		UserID:     currentUserID(r),
+		Title:      note.Title,
+		BodyFormat: bodyFormat,
+		Kind:       kind,
 	}
 
 	if note.Body != nil {
@@ -85,198 +138,322 @@ func (h *UserHandler) createNote(w http.ResponseWriter, r *http.Request) {
 		params.Body = sql.NullString{Valid: false}
 	}
 
+	// Una checklist se crea con un ítem por cada línea del cuerpo
+	var items []checklist.Item
+	if kind == kindChecklist {
+		items = checklist.Parse(params.Body.String)
+		params.Body = sql.NullString{String: checklist.Format(items), Valid: true}
+		params.BodyFormat = render.Markdown
+	}
+
 	if note.FolderID != nil {
+		if !h.ownsFolder(w, r, *note.FolderID) {
+			return
+		}
 		params.FolderID = sql.NullInt32{Int32: *note.FolderID, Valid: true}
 	} else {
 		params.FolderID = sql.NullInt32{Valid: false}
 	}
 
 	// Crear la nota en la base de datos
-	createdNote, err := h.queries.CreateNote(ctx, params)
+	var createdNote sqlc.CreateNoteRow
+	err := h.store.InTx(ctx, func(q sqlc.Querier) error {
+		var err error
+		createdNote, err = q.CreateNote(ctx, params)
+		if err != nil {
+			return err
+		}
+		return createNoteItems(ctx, q, createdNote.ID, items)
+	})
 	if err != nil {
-		http.Error(w, "Error al crear nota: "+err.Error(), http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error al crear nota")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	err = json.NewEncoder(w).Encode(createdNote)
+	err = json.NewEncoder(w).Encode(newNoteDTO(sqlc.GetNoteRow(createdNote)))
 	if err != nil {
-		http.Error(w, "Error al codificar JSON", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error al codificar JSON")
 		return
 	}
 }
 
+// getNoteByID devuelve la nota con sus etiquetas o, con ?format=html, solo
+// el cuerpo ya convertido a HTML.
 func (h *UserHandler) getNoteByID(w http.ResponseWriter, r *http.Request) {
-	// Obtener ID desde URL
-	idStr := r.URL.Path[len("/api/notes/"):]
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+	id, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "html" {
+		writeValidationError(w, r, fieldError{"format", "format tiene que ser json o html"})
 		return
 	}
 	// Buscar en la base de datos
-	note, err := h.queries.GetNote(r.Context(), int32(id))
+	note, err := h.store.GetNote(r.Context(), sqlc.GetNoteParams{ID: id, UserID: currentUserID(r)})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			http.Error(w, "No encontrado", http.StatusNotFound)
+			writeNotFound(w, r, "No encontrado")
 			return
 		}
-		http.Error(w, "Error interno", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error interno")
+		return
+	}
+	if format == "html" {
+		h.writeNoteHTML(w, r, note)
 		return
 	}
 
+	tags, err := h.store.ListNoteTags(r.Context(), note.ID)
+	if err != nil {
+		writeInternalError(w, r, err, "Error interno")
+		return
+	}
+	items, err := h.store.ListNoteItems(r.Context(), note.ID)
+	if err != nil {
+		writeInternalError(w, r, err, "Error interno")
+		return
+	}
+	response := noteWithTagsDTO{
+		noteDTO: newNoteDTO(note),
+		Tags:    mapSlice(tags, newTagDTO),
+		Items:   mapSlice(items, newNoteItemDTO),
+	}
+
+	w.Header().Set("ETag", etag(note.Version))
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(note)
+	err = json.NewEncoder(w).Encode(response)
 	if err != nil {
-		http.Error(w, "Error al codificar JSON", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error al codificar JSON")
 		return
 	}
 
 }
 
 func (h *UserHandler) updateNote(w http.ResponseWriter, r *http.Request) {
-	// Obtener ID desde URL
-	idStr := r.URL.Path[len("/api/notes/"):]
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+	id, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	ifMatch, ok := ifMatchVersion(w, r)
+	if !ok {
 		return
 	}
 	// Buscar en la base de datos
-	note, err := h.queries.GetNote(r.Context(), int32(id))
+	note, err := h.store.GetNote(r.Context(), sqlc.GetNoteParams{ID: id, UserID: currentUserID(r)})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			http.Error(w, "No encontrado", http.StatusNotFound)
+			writeNotFound(w, r, "No encontrado")
 			return
 		}
-		http.Error(w, "Error interno", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error interno")
 		return
 	}
-	// codigo para updatear nota
-	var input struct {
-		Title    string  `json:"title"`
-		Body     *string `json:"body"`
-		FolderID *int32  `json:"folder_id"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Error al decodificar JSON: "+err.Error(), http.StatusBadRequest)
+	if !ifMatch.matches(note.Version) {
+		writeStale(w, note.Version, newNoteDTO(note))
 		return
 	}
 	params := sqlc.UpdateNoteParams{
-		ID:    int32(id),
-		Title: input.Title,
+		ID:      id,
+		UserID:  currentUserID(r),
+		Version: note.Version,
 	}
 
-	if input.Body != nil {
-		params.Body = sql.NullString{String: *input.Body, Valid: true}
+	if r.Method == "PATCH" {
+		// JSON Merge Patch: lo que no viene en el body queda como está
+		patch, ok := decodeMergePatch(w, r)
+		if !ok {
+			return
+		}
+		params.Title, params.Body, params.FolderID = note.Title, note.Body, note.FolderID
+		params.BodyFormat = note.BodyFormat
+		err = errors.Join(
+			patch.string("title", &params.Title),
+			patch.nullString("body", &params.Body),
+			patch.string("body_format", &params.BodyFormat),
+			patch.nullInt32("folder_id", &params.FolderID),
+		)
+		if err == nil && !render.Valid(params.BodyFormat) {
+			err = errInvalidBodyFormat
+		}
+		if err != nil {
+			writeBadRequest(w, r, err)
+			return
+		}
+		// El cuerpo de una checklist sale de sus ítems
+		if note.Kind == kindChecklist && (params.Body != note.Body || params.BodyFormat != note.BodyFormat) {
+			writeValidationError(w, r, fieldError{"body", "El cuerpo de una checklist no se edita; se cambian sus ítems"})
+			return
+		}
 	} else {
-		params.Body = sql.NullString{Valid: false}
+		// PUT reemplaza la nota entera: lo que no viene queda en NULL
+		var input struct {
+			Title      string  `json:"title"`
+			Body       *string `json:"body"`
+			BodyFormat *string `json:"body_format"`
+			FolderID   *int32  `json:"folder_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeInvalidJSON(w, r)
+			return
+		}
+		params.Title = input.Title
+		if note.Kind == kindChecklist {
+			// El cuerpo de una checklist sale de sus ítems: PUT solo reemplaza
+			// el título y la carpeta, y body y body_format se ignoran
+			params.Body, params.BodyFormat = note.Body, note.BodyFormat
+		} else {
+			if params.BodyFormat, err = parseBodyFormat(input.BodyFormat); err != nil {
+				writeBadRequest(w, r, err)
+				return
+			}
+			if input.Body != nil {
+				params.Body = sql.NullString{String: *input.Body, Valid: true}
+			} else {
+				params.Body = sql.NullString{Valid: false}
+			}
+		}
+
+		if input.FolderID != nil {
+			params.FolderID = sql.NullInt32{Int32: *input.FolderID, Valid: true}
+		} else {
+			params.FolderID = sql.NullInt32{Valid: false}
+		}
 	}
 
-	if input.FolderID != nil {
-		params.FolderID = sql.NullInt32{Int32: *input.FolderID, Valid: true}
-	} else {
-		params.FolderID = sql.NullInt32{Valid: false}
+	if params.FolderID.Valid && params.FolderID != note.FolderID && !h.ownsFolder(w, r, params.FolderID.Int32) {
+		return
 	}
 
-	err = h.queries.UpdateNote(r.Context(), params)
+	// El contenido anterior queda guardado como revisión
+	var updated sqlc.UpdateNoteRow
+	err = h.store.InTx(r.Context(), func(q sqlc.Querier) error {
+		if err := h.saveNoteRevision(r.Context(), q, params.UserID, params.ID, params.Title, params.Body, params.BodyFormat); err != nil {
+			return err
+		}
+		updated, err = q.UpdateNote(r.Context(), params)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// La nota se borró o cambió de versión desde que la leímos
+		h.writeStaleNote(w, r, params.ID)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Error al actualizar la nota", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error al actualizar la nota")
 		return
 	}
 
+	w.Header().Set("ETag", etag(updated.Version))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	err = json.NewEncoder(w).Encode(note)
+	err = json.NewEncoder(w).Encode(newNoteDTO(sqlc.GetNoteRow(updated)))
 	if err != nil {
-		http.Error(w, "Error al codificar JSON", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error al codificar JSON")
 		return
 	}
 
 }
 
 func (h *UserHandler) deleteNote(w http.ResponseWriter, r *http.Request) {
-	idStr := r.URL.Path[len("/api/notes/"):]
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+	id, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	version, ok := h.noteVersion(w, r, id)
+	if !ok {
 		return
 	}
-	err = h.queries.DeleteNote(r.Context(), int32(id))
+	// La nota va a la papelera; el borrado definitivo es DELETE /api/v1/trash/notes/{id}
+	deleted, err := h.store.TrashNote(r.Context(), sqlc.TrashNoteParams{
+		ID:      id,
+		UserID:  currentUserID(r),
+		Version: version,
+	})
 	if err != nil {
-		http.Error(w, "Error al borrar la nota", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error al borrar la nota")
+		return
+	}
+	if deleted == 0 {
+		h.writeStaleNote(w, r, id)
+		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *UserHandler) FoldersHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("📌 NoteHandler llamado con método:", r.Method)
-	switch r.Method {
-	case "GET":
-		h.getFolders(w, r)
-	case "POST":
-		h.createFolder(w, r)
-	default:
-		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+// getFolders lista las carpetas paginadas. Acepta limit, cursor, sort (name o
+// created_at), order (asc o desc) y los filtros parent_folder_id y created_after.
+func (h *UserHandler) getFolders(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	req, err := parsePageRequest(r, "name", "created_at")
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	cursorTime, err := req.cursorTime()
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
 	}
-}
 
-func (h *UserHandler) FolderHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("📌 NoteHandler llamado con método:", r.Method)
-	switch r.Method {
-	case "GET":
-		h.getFolderByID(w, r)
-	case "PUT":
-		h.updateFolder(w, r)
-	case "POST":
-		h.createFolder(w, r)
-	case "DELETE":
-		h.deleteFolder(w, r)
-	default:
-		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+	params := sqlc.ListFoldersParams{
+		UserID:     currentUserID(r),
+		HasCursor:  req.Cursor != nil,
+		SortBy:     req.SortBy,
+		Descending: req.Descending,
+		CursorText: req.cursorText(),
+		CursorID:   req.cursorID(),
+		CursorTime: cursorTime,
+		PageSize:   req.fetchSize(),
+	}
+	if params.ParentFolderID, err = parseIDFilter(r, "parent_folder_id"); err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+	if params.CreatedAfter, err = parseTimeFilter(r, "created_after"); err != nil {
+		writeBadRequest(w, r, err)
+		return
 	}
-}
 
-func (h *UserHandler) getFolders(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
-	folders, err := h.queries.ListFolders(ctx)
+	folders, err := h.store.ListFolders(ctx, params)
 	if err != nil {
-		http.Error(w, "Error al listar carpetas: "+err.Error(), http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error al listar carpetas")
 		return
 	}
+	result := newPage(req, folders, func(f sqlc.Folder) (string, int32) {
+		if req.SortBy == "created_at" {
+			return formatCursorTime(f.CreatedAt), f.ID
+		}
+		return f.Name, f.ID
+	})
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(folders)
+	json.NewEncoder(w).Encode(mapPage(result, newFolderDTO))
 }
 
 func (h *UserHandler) createFolder(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := r.Context()
 
 	var folder struct {
-		UserID         *int32  `json:"user_id"`
 		Name           string  `json:"name"`
 		Description    *string `json:"description"`
 		ParentFolderID *int32  `json:"parent_folder_id"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&folder); err != nil {
-		http.Error(w, "Error al decodificar JSON: "+err.Error(), http.StatusBadRequest)
+		writeInvalidJSON(w, r)
 		return
 	}
 
 	if folder.Name == "" {
-		http.Error(w, "El nombre es obligatorio", http.StatusBadRequest)
+		writeValidationError(w, r, fieldError{"name", "El nombre es obligatorio"})
 		return
 	}
 
 	params := sqlc.CreateFolderParams{
-		Name: folder.Name,
-	}
-
-	if folder.UserID != nil {
-		params.UserID = sql.NullInt32{Int32: *folder.UserID, Valid: true}
-	} else {
-		params.UserID = sql.NullInt32{Valid: false}
+		UserID: currentUserID(r),
+		Name:   folder.Name,
 	}
 
 	if folder.Description != nil {
@@ -286,174 +463,306 @@ func (h *UserHandler) createFolder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if folder.ParentFolderID != nil {
+		if !h.ownsFolder(w, r, *folder.ParentFolderID) {
+			return
+		}
 		params.ParentFolderID = sql.NullInt32{Int32: *folder.ParentFolderID, Valid: true}
 	} else {
 		params.ParentFolderID = sql.NullInt32{Valid: false}
 	}
 
-	createdFolder, err := h.queries.CreateFolder(ctx, params)
+	createdFolder, err := h.store.CreateFolder(ctx, params)
 	if err != nil {
-		http.Error(w, "Error al crear carpeta: "+err.Error(), http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error al crear carpeta")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	err = json.NewEncoder(w).Encode(createdFolder)
+	err = json.NewEncoder(w).Encode(newFolderDTO(createdFolder))
 	if err != nil {
-		http.Error(w, "Error al codificar JSON", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error al codificar JSON")
 		return
 	}
 }
 
 func (h *UserHandler) getFolderByID(w http.ResponseWriter, r *http.Request) {
-	idStr := r.URL.Path[len("/api/folders/"):]
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+	id, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
 		return
 	}
 	// Buscar en la base de datos
-	folder, err := h.queries.GetFolder(r.Context(), int32(id))
+	folder, err := h.store.GetFolder(r.Context(), sqlc.GetFolderParams{ID: id, UserID: currentUserID(r)})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			http.Error(w, "No encontrado", http.StatusNotFound)
+			writeNotFound(w, r, "No encontrado")
 			return
 		}
-		http.Error(w, "Error interno", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error interno")
 		return
 	}
 
+	w.Header().Set("ETag", etag(folder.Version))
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(folder)
+	err = json.NewEncoder(w).Encode(newFolderDTO(folder))
 	if err != nil {
-		http.Error(w, "Error al codificar JSON", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error al codificar JSON")
 		return
 	}
 }
 
 func (h *UserHandler) updateFolder(w http.ResponseWriter, r *http.Request) {
-	// Obtener ID desde URL
-	idStr := r.URL.Path[len("/api/folders/"):]
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+	id, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	ifMatch, ok := ifMatchVersion(w, r)
+	if !ok {
 		return
 	}
 	// Buscar en la base de datos
-	folder, err := h.queries.GetFolder(r.Context(), int32(id))
+	folder, err := h.store.GetFolder(r.Context(), sqlc.GetFolderParams{ID: id, UserID: currentUserID(r)})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			http.Error(w, "No encontrado", http.StatusNotFound)
+			writeNotFound(w, r, "No encontrado")
 			return
 		}
-		http.Error(w, "Error interno", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error interno")
 		return
 	}
-	// codigo para updatear nota
-	var input struct {
-		Name           string  `json:"name"`
-		Description    *string `json:"description"`
-		ParentFolderID *int32  `json:"parent_folder_id"`
-		UserID         *int32  `json:"user_id"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Error al decodificar JSON: "+err.Error(), http.StatusBadRequest)
+	if !ifMatch.matches(folder.Version) {
+		writeStale(w, folder.Version, newFolderDTO(folder))
 		return
 	}
 	params := sqlc.UpdateFolderParams{
-		ID:   int32(id),
-		Name: input.Name,
+		ID:      id,
+		UserID:  currentUserID(r),
+		Version: folder.Version,
 	}
 
-	if input.Description != nil {
-		params.Description = sql.NullString{String: *input.Description, Valid: true}
+	if r.Method == "PATCH" {
+		// JSON Merge Patch: lo que no viene en el body queda como está
+		patch, ok := decodeMergePatch(w, r)
+		if !ok {
+			return
+		}
+		params.Name, params.Description, params.ParentFolderID = folder.Name, folder.Description, folder.ParentFolderID
+		err = errors.Join(
+			patch.string("name", &params.Name),
+			patch.nullString("description", &params.Description),
+			patch.nullInt32("parent_folder_id", &params.ParentFolderID),
+		)
+		if err != nil {
+			writeBadRequest(w, r, err)
+			return
+		}
 	} else {
-		params.Description = sql.NullString{Valid: false}
-	}
+		// PUT reemplaza la carpeta entera: lo que no viene queda en NULL
+		var input struct {
+			Name           string  `json:"name"`
+			Description    *string `json:"description"`
+			ParentFolderID *int32  `json:"parent_folder_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeInvalidJSON(w, r)
+			return
+		}
+		params.Name = input.Name
 
-	if input.ParentFolderID != nil {
-		params.ParentFolderID = sql.NullInt32{Int32: *input.ParentFolderID, Valid: true}
-	} else {
-		params.ParentFolderID = sql.NullInt32{Valid: false}
-	}
+		if input.Description != nil {
+			params.Description = sql.NullString{String: *input.Description, Valid: true}
+		} else {
+			params.Description = sql.NullString{Valid: false}
+		}
 
-	if input.UserID != nil {
-		params.UserID = sql.NullInt32{Int32: *input.UserID, Valid: true}
-	} else {
-		params.UserID = sql.NullInt32{Valid: false}
+		if input.ParentFolderID != nil {
+			params.ParentFolderID = sql.NullInt32{Int32: *input.ParentFolderID, Valid: true}
+		} else {
+			params.ParentFolderID = sql.NullInt32{Valid: false}
+		}
 	}
 
-	err = h.queries.UpdateFolder(r.Context(), params)
+	// Cambiar parent_folder_id es un movimiento: se valida igual que en /move
+	var updated sqlc.Folder
+	err = h.store.InTx(r.Context(), func(q sqlc.Querier) error {
+		if err := checkFolderMove(r.Context(), q, params.UserID, params.ID, params.ParentFolderID); err != nil {
+			return err
+		}
+		updated, err = q.UpdateFolder(r.Context(), params)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// La carpeta cambió de versión desde que la leímos
+		h.writeStaleFolder(w, r, params.ID)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Error al actualizar la Carpeta", http.StatusInternalServerError)
+		writeMoveError(w, r, err, "Error al actualizar la Carpeta")
 		return
 	}
 
+	w.Header().Set("ETag", etag(updated.Version))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	err = json.NewEncoder(w).Encode(folder)
+	err = json.NewEncoder(w).Encode(newFolderDTO(updated))
 	if err != nil {
-		http.Error(w, "Error al codificar JSON", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error al codificar JSON")
 		return
 	}
 }
 
 func (h *UserHandler) deleteFolder(w http.ResponseWriter, r *http.Request) {
-	idStr := r.URL.Path[len("/api/folders/"):]
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+	id, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	version, ok := h.folderVersion(w, r, id)
+	if !ok {
 		return
 	}
-	err = h.queries.DeleteFolder(r.Context(), int32(id))
+	// La carpeta va a la papelera junto con sus subcarpetas y notas
+	deleted, err := h.store.TrashFolder(r.Context(), sqlc.TrashFolderParams{
+		ID:        id,
+		UserID:    currentUserID(r),
+		Version:   version,
+		DeletedAt: time.Now(),
+	})
 	if err != nil {
-		http.Error(w, "Error al borrar la carpeta", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error al borrar la carpeta")
+		return
+	}
+	if deleted == 0 {
+		h.writeStaleFolder(w, r, id)
+		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// ============= USERS HANDLERS =============
+// noteVersion devuelve la versión contra la que borrar la nota: la del
+// If-Match o, con If-Match: *, la actual.
+func (h *UserHandler) noteVersion(w http.ResponseWriter, r *http.Request, id int32) (int32, bool) {
+	ifMatch, ok := ifMatchVersion(w, r)
+	if !ok || !ifMatch.any {
+		return ifMatch.version, ok
+	}
+	note, err := h.store.GetNote(r.Context(), sqlc.GetNoteParams{ID: id, UserID: currentUserID(r)})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeNotFound(w, r, "No encontrado")
+			return 0, false
+		}
+		writeInternalError(w, r, err, "Error interno")
+		return 0, false
+	}
+	return note.Version, true
+}
 
-func (h *UserHandler) UsersHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("📌 UsersHandler llamado con método:", r.Method)
-	switch r.Method {
-	case "GET":
-		h.getUsers(w, r)
-	case "POST":
-		h.createUser(w, r)
-	default:
-		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+// folderVersion es como noteVersion pero para carpetas.
+func (h *UserHandler) folderVersion(w http.ResponseWriter, r *http.Request, id int32) (int32, bool) {
+	ifMatch, ok := ifMatchVersion(w, r)
+	if !ok || !ifMatch.any {
+		return ifMatch.version, ok
 	}
+	folder, err := h.store.GetFolder(r.Context(), sqlc.GetFolderParams{ID: id, UserID: currentUserID(r)})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeNotFound(w, r, "No encontrado")
+			return 0, false
+		}
+		writeInternalError(w, r, err, "Error interno")
+		return 0, false
+	}
+	return folder.Version, true
 }
 
-func (h *UserHandler) SingleUserHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("📌 SingleUserHandler llamado con método:", r.Method)
-	switch r.Method {
-	case "GET":
-		h.getUserByID(w, r)
-	case "PUT":
-		h.updateUser(w, r)
-	case "DELETE":
-		h.deleteUser(w, r)
-	default:
-		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+// writeStaleNote se usa cuando un UPDATE con versión no devolvió ninguna fila:
+// responde 404 si la nota ya no existe o 412 con la copia actual.
+func (h *UserHandler) writeStaleNote(w http.ResponseWriter, r *http.Request, id int32) {
+	note, err := h.store.GetNote(r.Context(), sqlc.GetNoteParams{ID: id, UserID: currentUserID(r)})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeNotFound(w, r, "No encontrado")
+			return
+		}
+		writeInternalError(w, r, err, "Error interno")
+		return
 	}
+	writeStale(w, note.Version, newNoteDTO(note))
 }
 
+// writeStaleFolder es como writeStaleNote pero para carpetas.
+func (h *UserHandler) writeStaleFolder(w http.ResponseWriter, r *http.Request, id int32) {
+	folder, err := h.store.GetFolder(r.Context(), sqlc.GetFolderParams{ID: id, UserID: currentUserID(r)})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeNotFound(w, r, "No encontrado")
+			return
+		}
+		writeInternalError(w, r, err, "Error interno")
+		return
+	}
+	writeStale(w, folder.Version, newFolderDTO(folder))
+}
+
+// ownsFolder verifica que la carpeta exista y sea del usuario autenticado.
+// Si no, escribe la respuesta de error y devuelve false.
+func (h *UserHandler) ownsFolder(w http.ResponseWriter, r *http.Request, folderID int32) bool {
+	_, err := h.store.GetFolder(r.Context(), sqlc.GetFolderParams{ID: folderID, UserID: currentUserID(r)})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeNotFound(w, r, "Carpeta no encontrada")
+			return false
+		}
+		writeInternalError(w, r, err, "Error interno")
+		return false
+	}
+	return true
+}
+
+// ============= USERS HANDLERS =============
+
+// getUsers lista los usuarios paginados. Cada uno solo se ve a sí mismo, como
+// en getUserByID. Acepta limit, cursor, sort (username o created_at) y order
+// (asc o desc).
 func (h *UserHandler) getUsers(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
-	users, err := h.queries.ListUsers(ctx)
+	ctx := r.Context()
+	req, err := parsePageRequest(r, "username", "created_at")
 	if err != nil {
-		http.Error(w, "Error al listar usuarios: "+err.Error(), http.StatusInternalServerError)
+		writeBadRequest(w, r, err)
 		return
 	}
+	cursorTime, err := req.cursorTime()
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	users, err := h.store.ListUsers(ctx, sqlc.ListUsersParams{
+		UserID:     currentUserID(r),
+		HasCursor:  req.Cursor != nil,
+		SortBy:     req.SortBy,
+		Descending: req.Descending,
+		CursorText: req.cursorText(),
+		CursorID:   req.cursorID(),
+		CursorTime: cursorTime,
+		PageSize:   req.fetchSize(),
+	})
+	if err != nil {
+		writeInternalError(w, r, err, "Error al listar usuarios")
+		return
+	}
+	result := newPage(req, users, func(u sqlc.ListUsersRow) (string, int32) {
+		if req.SortBy == "created_at" {
+			return formatCursorTime(u.CreatedAt), u.ID
+		}
+		return u.Username, u.ID
+	})
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(users)
+	json.NewEncoder(w).Encode(mapPage(result, newUserDTO))
 }
 
 func (h *UserHandler) createUser(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := r.Context()
 
 	var user struct {
 		Username string `json:"username"`
@@ -462,84 +771,99 @@ func (h *UserHandler) createUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, "Error al decodificar JSON: "+err.Error(), http.StatusBadRequest)
+		writeInvalidJSON(w, r)
+		return
+	}
+
+	if missing := requiredFields("username", user.Username, "email", user.Email, "password", user.Password); missing != nil {
+		writeValidationError(w, r, missing...)
 		return
 	}
 
-	if user.Username == "" || user.Email == "" || user.Password == "" {
-		http.Error(w, "Username, email y password son obligatorios", http.StatusBadRequest)
+	hashed, err := password.Hash(user.Password)
+	if err != nil {
+		writeInternalError(w, r, err, "Error al procesar la contraseña")
 		return
 	}
 
-	// TODO: Aquí deberías hashear la contraseña con bcrypt
-	// Por ahora la guardamos en texto plano (NO SEGURO para producción)
 	params := sqlc.CreateUserParams{
 		Username: user.Username,
 		Email:    user.Email,
-		Password: user.Password,
+		Password: hashed,
 	}
 
-	createdUser, err := h.queries.CreateUser(ctx, params)
+	createdUser, err := h.store.CreateUser(ctx, params)
 	if err != nil {
-		http.Error(w, "Error al crear usuario: "+err.Error(), http.StatusInternalServerError)
+		if store.IsUniqueViolation(err) {
+			writeProblem(w, r, http.StatusConflict, codeAlreadyExists, "Ya existe un usuario con ese nombre o email")
+			return
+		}
+		writeInternalError(w, r, err, "Error al crear usuario")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(createdUser)
+	json.NewEncoder(w).Encode(newUserDTO(sqlc.ListUsersRow(createdUser)))
+}
+
+// RegisterHandler permite crear una cuenta sin estar autenticado.
+func (h *UserHandler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	h.createUser(w, r)
 }
 
 func (h *UserHandler) getUserByID(w http.ResponseWriter, r *http.Request) {
-	idStr := r.URL.Path[len("/api/users/"):]
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+	id, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+
+	if id != currentUserID(r) {
+		writeProblem(w, r, http.StatusForbidden, codeForbidden, "No podés ver otro usuario")
 		return
 	}
 
-	user, err := h.queries.GetUser(r.Context(), int32(id))
+	user, err := h.store.GetUser(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			http.Error(w, "Usuario no encontrado", http.StatusNotFound)
+			writeNotFound(w, r, "Usuario no encontrado")
 			return
 		}
-		http.Error(w, "Error interno", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error interno")
 		return
 	}
 
 	// No devolver la contraseña en la respuesta
-	response := struct {
-		ID        int32  `json:"id"`
-		Username  string `json:"username"`
-		Email     string `json:"email"`
-		CreatedAt string `json:"created_at,omitempty"`
-	}{
-		ID:       user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-	}
+	response := newUserDTO(sqlc.ListUsersRow{
+		ID:        user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt,
+	})
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
 func (h *UserHandler) updateUser(w http.ResponseWriter, r *http.Request) {
-	idStr := r.URL.Path[len("/api/users/"):]
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+	id, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+
+	if id != currentUserID(r) {
+		writeProblem(w, r, http.StatusForbidden, codeForbidden, "No podés modificar otro usuario")
 		return
 	}
 
 	// Verificar que el usuario existe
-	_, err = h.queries.GetUser(r.Context(), int32(id))
+	_, err := h.store.GetUser(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			http.Error(w, "Usuario no encontrado", http.StatusNotFound)
+			writeNotFound(w, r, "Usuario no encontrado")
 			return
 		}
-		http.Error(w, "Error interno", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error interno")
 		return
 	}
 
@@ -550,26 +874,35 @@ func (h *UserHandler) updateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Error al decodificar JSON: "+err.Error(), http.StatusBadRequest)
+		writeInvalidJSON(w, r)
 		return
 	}
 
-	if input.Username == "" || input.Email == "" || input.Password == "" {
-		http.Error(w, "Username, email y password son obligatorios", http.StatusBadRequest)
+	if missing := requiredFields("username", input.Username, "email", input.Email, "password", input.Password); missing != nil {
+		writeValidationError(w, r, missing...)
+		return
+	}
+
+	hashed, err := password.Hash(input.Password)
+	if err != nil {
+		writeInternalError(w, r, err, "Error al procesar la contraseña")
 		return
 	}
 
-	// TODO: Hashear la contraseña con bcrypt
 	params := sqlc.UpdateUserParams{
-		ID:       int32(id),
+		ID:       id,
 		Username: input.Username,
 		Email:    input.Email,
-		Password: input.Password,
+		Password: hashed,
 	}
 
-	err = h.queries.UpdateUser(r.Context(), params)
+	err = h.store.UpdateUser(r.Context(), params)
 	if err != nil {
-		http.Error(w, "Error al actualizar usuario: "+err.Error(), http.StatusInternalServerError)
+		if store.IsUniqueViolation(err) {
+			writeProblem(w, r, http.StatusConflict, codeAlreadyExists, "Ya existe un usuario con ese nombre o email")
+			return
+		}
+		writeInternalError(w, r, err, "Error al actualizar usuario")
 		return
 	}
 
@@ -579,16 +912,19 @@ func (h *UserHandler) updateUser(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *UserHandler) deleteUser(w http.ResponseWriter, r *http.Request) {
-	idStr := r.URL.Path[len("/api/users/"):]
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+	id, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
 		return
 	}
 
-	err = h.queries.DeleteUser(r.Context(), int32(id))
+	if id != currentUserID(r) {
+		writeProblem(w, r, http.StatusForbidden, codeForbidden, "No podés borrar otro usuario")
+		return
+	}
+
+	err := h.store.DeleteUser(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Error al borrar usuario: "+err.Error(), http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error al borrar usuario")
 		return
 	}
 
@@ -598,12 +934,7 @@ func (h *UserHandler) deleteUser(w http.ResponseWriter, r *http.Request) {
 
 // Login handler
 func (h *UserHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
-		return
-	}
-
-	ctx := context.Background()
+	ctx := r.Context()
 
 	var credentials struct {
 		Username string `json:"username"`
@@ -611,43 +942,64 @@ func (h *UserHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
-		http.Error(w, "Error al decodificar JSON: "+err.Error(), http.StatusBadRequest)
+		writeInvalidJSON(w, r)
 		return
 	}
 
-	if credentials.Username == "" || credentials.Password == "" {
-		http.Error(w, "Username y password son obligatorios", http.StatusBadRequest)
+	if missing := requiredFields("username", credentials.Username, "password", credentials.Password); missing != nil {
+		writeValidationError(w, r, missing...)
 		return
 	}
 
-	user, err := h.queries.GetUserByUsername(ctx, credentials.Username)
+	user, err := h.store.GetUserByUsername(ctx, credentials.Username)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			http.Error(w, "Credenciales inválidas", http.StatusUnauthorized)
+			writeProblem(w, r, http.StatusUnauthorized, codeInvalidCredentials, "Credenciales inválidas")
 			return
 		}
-		http.Error(w, "Error interno", http.StatusInternalServerError)
+		writeInternalError(w, r, err, "Error interno")
 		return
 	}
 
-	// TODO: Comparar con bcrypt.CompareHashAndPassword
-	// Por ahora comparación en texto plano (NO SEGURO)
-	if user.Password != credentials.Password {
-		http.Error(w, "Credenciales inválidas", http.StatusUnauthorized)
+	ok, needsRehash, err := password.Verify(credentials.Password, user.Password)
+	if err != nil {
+		writeInternalError(w, r, err, "Error interno")
+		return
+	}
+	if !ok {
+		writeProblem(w, r, http.StatusUnauthorized, codeInvalidCredentials, "Credenciales inválidas")
+		return
+	}
+
+	// Migrar hashes legados (texto plano, bcrypt o parámetros viejos)
+	if needsRehash {
+		if hashed, err := password.Hash(credentials.Password); err != nil {
+			slog.Error("No se pudo rehashear la contraseña", "request_id", requestIDFrom(ctx), "user_id", user.ID, "err", err)
+		} else if err := h.store.UpdateUserPassword(ctx, sqlc.UpdateUserPasswordParams{
+			ID:       user.ID,
+			Password: hashed,
+		}); err != nil {
+			slog.Error("No se pudo guardar el hash nuevo de la contraseña", "request_id", requestIDFrom(ctx), "user_id", user.ID, "err", err)
+		}
+	}
+
+	if err := h.startSession(w, r, user.ID); err != nil {
+		writeInternalError(w, r, err, "Error al iniciar sesión")
 		return
 	}
 
 	// Login exitoso - devolver datos del usuario (sin password)
 	response := struct {
-		ID       int32  `json:"id"`
-		Username string `json:"username"`
-		Email    string `json:"email"`
-		Message  string `json:"message"`
+		userDTO
+		Message string `json:"message"`
 	}{
-		ID:       user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		Message:  "Login exitoso",
+		userDTO: newUserDTO(sqlc.ListUsersRow{
+			ID:        user.ID,
+			Username:  user.Username,
+			Email:     user.Email,
+			CreatedAt: user.CreatedAt,
+		}),
+		Message: "Login exitoso",
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"tpeweb.com/servidor-go/store"
+)
+
+// client manda requests a la API sobre un Store en memoria, con la cookie de
+// sesión del usuario con el que se logueó.
+type client struct {
+	t       *testing.T
+	handler http.Handler
+	cookie  *http.Cookie
+}
+
+func newTestAPI(t *testing.T) (*UserHandler, *store.Memory) {
+	t.Helper()
+	s := store.NewMemory()
+	return NewUserHandler(s), s
+}
+
+func newClient(t *testing.T, h *UserHandler) *client {
+	return &client{t: t, handler: h.Routes()}
+}
+
+// do manda la request; body se codifica como JSON salvo que sea un string.
+// headers son pares nombre, valor.
+func (c *client) do(method, path string, body any, headers ...string) *httptest.ResponseRecorder {
+	c.t.Helper()
+	var reader *bytes.Reader
+	switch b := body.(type) {
+	case nil:
+		reader = bytes.NewReader(nil)
+	case string:
+		reader = bytes.NewReader([]byte(b))
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	if c.cookie != nil {
+		req.AddCookie(c.cookie)
+	}
+	rec := httptest.NewRecorder()
+	c.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// signUp registra al usuario y se loguea con él.
+func (c *client) signUp(username string) {
+	c.t.Helper()
+	rec := c.do("POST", "/api/v1/register", map[string]string{
+		"username": username,
+		"email":    username + "@example.com",
+		"password": "secreto123",
+	})
+	expectStatus(c.t, rec, http.StatusCreated)
+
+	rec = c.do("POST", "/api/v1/login", map[string]string{"username": username, "password": "secreto123"})
+	expectStatus(c.t, rec, http.StatusOK)
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == sessionCookieName {
+			c.cookie = cookie
+			return
+		}
+	}
+	c.t.Fatal("el login no devolvió la cookie de sesión")
+}
+
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if rec.Code != want {
+		t.Fatalf("status = %d, se esperaba %d; body: %s", rec.Code, want, rec.Body)
+	}
+}
+
+// expectProblem verifica que la respuesta sea un problem+json con ese status y code.
+func expectProblem(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) problem {
+	t.Helper()
+	expectStatus(t, rec, status)
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("Content-Type = %q, se esperaba application/problem+json", ct)
+	}
+	var p problem
+	decode(t, rec, &p)
+	if p.Code != code {
+		t.Fatalf("code = %q, se esperaba %q", p.Code, code)
+	}
+	return p
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+		t.Fatalf("respuesta con JSON inválido: %v", err)
+	}
+}
+
+func path(format string, id int32) string {
+	return format + "/" + strconv.Itoa(int(id))
+}
+
+func TestLogin(t *testing.T) {
+	h, _ := newTestAPI(t)
+	c := newClient(t, h)
+	c.signUp("ana")
+
+	anon := newClient(t, h)
+	rec := anon.do("POST", "/api/v1/login", map[string]string{"username": "ana", "password": "otra"})
+	expectProblem(t, rec, http.StatusUnauthorized, codeInvalidCredentials)
+
+	rec = anon.do("POST", "/api/v1/login", map[string]string{"username": "nadie", "password": "secreto123"})
+	expectProblem(t, rec, http.StatusUnauthorized, codeInvalidCredentials)
+
+	rec = anon.do("POST", "/api/v1/login", map[string]string{"username": "ana"})
+	p := expectProblem(t, rec, http.StatusBadRequest, codeValidationFailed)
+	if len(p.Errors) != 1 || p.Errors[0].Field != "password" {
+		t.Fatalf("errors = %+v, se esperaba solo password", p.Errors)
+	}
+
+	rec = anon.do("POST", "/api/v1/login", "{")
+	expectProblem(t, rec, http.StatusBadRequest, codeInvalidJSON)
+}
+
+func TestRequireAuth(t *testing.T) {
+	h, _ := newTestAPI(t)
+	c := newClient(t, h)
+
+	rec := c.do("GET", "/api/v1/notes", nil)
+	expectProblem(t, rec, http.StatusUnauthorized, codeUnauthenticated)
+
+	c.signUp("ana")
+	rec = c.do("GET", "/api/v1/notes", nil)
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = c.do("POST", "/api/v1/logout", nil)
+	expectStatus(t, rec, http.StatusNoContent)
+	rec = c.do("GET", "/api/v1/notes", nil)
+	expectProblem(t, rec, http.StatusUnauthorized, codeUnauthenticated)
+}
+
+func TestSessionCookieSecure(t *testing.T) {
+	for _, secure := range []bool{true, false} {
+		h, _ := newTestAPI(t)
+		h.CookieSecure = secure
+		c := newClient(t, h)
+		c.signUp("ana")
+		if c.cookie.Secure != secure || !c.cookie.HttpOnly {
+			t.Fatalf("CookieSecure = %v: cookie de sesión %+v", secure, c.cookie)
+		}
+
+		rec := c.do("POST", "/api/v1/logout", nil)
+		expectStatus(t, rec, http.StatusNoContent)
+		if cookies := rec.Result().Cookies(); len(cookies) != 1 || cookies[0].Secure != secure {
+			t.Fatalf("CookieSecure = %v: cookie del logout %+v", secure, cookies)
+		}
+	}
+}
+
+func TestRoutingErrors(t *testing.T) {
+	h, _ := newTestAPI(t)
+	c := newClient(t, h)
+	c.signUp("ana")
+
+	rec := c.do("GET", "/api/v1/nada", nil)
+	expectProblem(t, rec, http.StatusNotFound, codeNotFound)
+
+	rec = c.do("PATCH", "/api/v1/tags/1", nil)
+	expectProblem(t, rec, http.StatusMethodNotAllowed, codeMethodNotAllowed)
+	if allow := rec.Header().Get("Allow"); allow != "DELETE, PUT" {
+		t.Fatalf("Allow = %q", allow)
+	}
+
+	rec = c.do("GET", "/api/v1/notes/abc", nil)
+	expectProblem(t, rec, http.StatusBadRequest, codeInvalidID)
+
+	// Las rutas viejas bajo /api siguen andando
+	rec = c.do("GET", "/api/notes", nil)
+	expectStatus(t, rec, http.StatusOK)
+}
+
+func TestUsers(t *testing.T) {
+	h, _ := newTestAPI(t)
+	ana := newClient(t, h)
+	ana.signUp("ana")
+	beto := newClient(t, h)
+	beto.signUp("beto")
+
+	var page struct {
+		Items []userDTO `json:"items"`
+	}
+	rec := beto.do("GET", "/api/v1/users?sort=username", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &page)
+	if len(page.Items) != 1 || page.Items[0].Username != "beto" {
+		t.Fatalf("usuarios = %+v", page.Items)
+	}
+	betoID := page.Items[0].ID
+
+	rec = ana.do("GET", path("/api/v1/users", betoID), nil)
+	expectProblem(t, rec, http.StatusForbidden, codeForbidden)
+	rec = ana.do("PUT", path("/api/v1/users", betoID), map[string]string{"username": "x", "email": "x@example.com"})
+	expectProblem(t, rec, http.StatusForbidden, codeForbidden)
+
+	rec = beto.do("DELETE", path("/api/v1/users", betoID), nil)
+	expectStatus(t, rec, http.StatusNoContent)
+
+	// Borrar el usuario borra sus sesiones
+	rec = beto.do("GET", "/api/v1/notes", nil)
+	expectProblem(t, rec, http.StatusUnauthorized, codeUnauthenticated)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"tpeweb.com/servidor-go/buildinfo"
+)
+
+// readyTimeout limita cuánto puede tardar GET /readyz en chequear la base.
+const readyTimeout = 2 * time.Second
+
+type readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Healthz atiende GET /healthz: responde 200 mientras el proceso esté vivo,
+// sin tocar la base.
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// Readyz atiende GET /readyz: responde 200 si la base contesta y no quedan
+// migraciones pendientes, y 503 si no. Los errores de la base quedan en el
+// log; en la respuesta solo sale "unavailable", porque el endpoint es público.
+func (h *UserHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	result := readiness{Status: "ok", Checks: map[string]string{}}
+	if err := h.store.Ping(ctx); err != nil {
+		slog.Error("readyz: la base no responde", "request_id", requestIDFrom(r.Context()), "err", err)
+		result.Status = "unavailable"
+		result.Checks["database"] = "unavailable"
+		result.Checks["migrations"] = "sin chequear"
+	} else {
+		result.Checks["database"] = "ok"
+		pending, err := h.store.PendingMigrations(ctx)
+		switch {
+		case err != nil:
+			slog.Error("readyz: no se pudieron leer las migraciones", "request_id", requestIDFrom(r.Context()), "err", err)
+			result.Status = "unavailable"
+			result.Checks["migrations"] = "unavailable"
+		case pending > 0:
+			result.Status = "unavailable"
+			result.Checks["migrations"] = fmt.Sprintf("%d pendientes", pending)
+		default:
+			result.Checks["migrations"] = "ok"
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if result.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// Version atiende GET /version con el commit y la fecha de compilación.
+func Version(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildinfo.Get())
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tpeweb.com/servidor-go/store"
+)
+
+// downStore es un Store cuya base no responde.
+type downStore struct {
+	store.Store
+}
+
+func (downStore) Ping(ctx context.Context) error {
+	return errors.New("dial tcp 10.0.0.7:5432: connect: connection refused")
+}
+
+func TestReadyz(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewUserHandler(store.NewMemory()).Readyz(rec, httptest.NewRequest("GET", "/readyz", nil))
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = httptest.NewRecorder()
+	NewUserHandler(downStore{store.NewMemory()}).Readyz(rec, httptest.NewRequest("GET", "/readyz", nil))
+	expectStatus(t, rec, http.StatusServiceUnavailable)
+	var got readiness
+	decode(t, rec, &got)
+	if got.Status != "unavailable" || got.Checks["database"] != "unavailable" {
+		t.Fatalf("readyz = %+v", got)
+	}
+	if strings.Contains(rec.Body.String(), "10.0.0.7") {
+		t.Fatalf("readyz muestra el error de la base: %s", rec.Body.String())
+	}
+}
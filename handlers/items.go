@@ -0,0 +1,473 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"tpeweb.com/servidor-go/checklist"
+	sqlc "tpeweb.com/servidor-go/db/sqlc"
+	"tpeweb.com/servidor-go/render"
+)
+
+// Tipos de nota, como se guardan en note.kind. En las checklists el cuerpo
+// es una lista de tareas en Markdown que se vuelve a armar cada vez que
+// cambian los ítems; no se edita directamente.
+const (
+	kindText      = "text"
+	kindChecklist = "checklist"
+)
+
+var (
+	errNotChecklist     = errors.New("la nota no es una checklist")
+	errChecklistRestore = errors.New("no se puede restaurar una revisión en una checklist")
+	errItemNotFound     = errors.New("ítem no encontrado")
+)
+
+// parseKind valida el kind de un create; si no viene, la nota es de texto.
+func parseKind(kind *string) (string, error) {
+	if kind == nil {
+		return kindText, nil
+	}
+	if *kind != kindText && *kind != kindChecklist {
+		return "", fieldError{"kind", "kind tiene que ser text o checklist"}
+	}
+	return *kind, nil
+}
+
+// itemInput son los campos de un ítem que manda el cliente. Los punteros
+// distinguen lo que no vino.
+type itemInput struct {
+	Text     *string `json:"text"`
+	Checked  *bool   `json:"checked"`
+	Indent   *int32  `json:"indent"`
+	Position *int32  `json:"position"`
+}
+
+func validateItem(text string, indent int32) error {
+	var errs []error
+	if strings.ContainsAny(text, "\r\n") {
+		errs = append(errs, fieldError{"text", "text no puede tener saltos de línea"})
+	}
+	if indent < 0 || indent > checklist.MaxIndent {
+		errs = append(errs, fieldError{"indent", "indent tiene que ser 0 o 1"})
+	}
+	return errors.Join(errs...)
+}
+
+// listNoteItems devuelve los ítems de una checklist en orden.
+func (h *UserHandler) listNoteItems(w http.ResponseWriter, r *http.Request) {
+	noteID, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	note, err := h.store.GetNote(r.Context(), sqlc.GetNoteParams{ID: noteID, UserID: currentUserID(r)})
+	if err != nil {
+		writeItemError(w, r, err, "Error interno")
+		return
+	}
+	items, err := h.store.ListNoteItems(r.Context(), note.ID)
+	if err != nil {
+		writeInternalError(w, r, err, "Error al listar los ítems")
+		return
+	}
+	w.Header().Set("ETag", etag(note.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapSlice(items, newNoteItemDTO))
+}
+
+// createNoteItem agrega un ítem al final o, si viene position, en ese lugar,
+// corriendo los que siguen.
+func (h *UserHandler) createNoteItem(w http.ResponseWriter, r *http.Request) {
+	noteID, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	var input itemInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeInvalidJSON(w, r)
+		return
+	}
+	params := sqlc.CreateNoteItemParams{NoteID: noteID}
+	if input.Text != nil {
+		params.Text = *input.Text
+	}
+	if input.Checked != nil {
+		params.Checked = *input.Checked
+	}
+	if input.Indent != nil {
+		params.Indent = *input.Indent
+	}
+	err := validateItem(params.Text, params.Indent)
+	if input.Position != nil && *input.Position < 0 {
+		err = errors.Join(err, fieldError{"position", "position no puede ser negativa"})
+	}
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	var item sqlc.NoteItem
+	note, err := h.changeItems(r.Context(), currentUserID(r), noteID, func(q sqlc.Querier) error {
+		next, err := q.NextNoteItemPosition(r.Context(), noteID)
+		if err != nil {
+			return err
+		}
+		params.Position = next
+		if input.Position != nil && *input.Position < next {
+			params.Position = *input.Position
+			err := q.ShiftNoteItems(r.Context(), sqlc.ShiftNoteItemsParams{NoteID: noteID, Position: params.Position})
+			if err != nil {
+				return err
+			}
+		}
+		item, err = q.CreateNoteItem(r.Context(), params)
+		return err
+	})
+	if err != nil {
+		writeItemError(w, r, err, "Error al agregar el ítem")
+		return
+	}
+
+	w.Header().Set("ETag", etag(note.Version))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newNoteItemDTO(item))
+}
+
+// updateNoteItem aplica un JSON Merge Patch con text, checked o indent; así
+// se tilda y destilda un ítem.
+func (h *UserHandler) updateNoteItem(w http.ResponseWriter, r *http.Request) {
+	noteID, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	itemID, ok := pathID(w, r, "item", "ID de ítem inválido")
+	if !ok {
+		return
+	}
+	patch, ok := decodeMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	var item sqlc.NoteItem
+	note, err := h.changeItems(r.Context(), currentUserID(r), noteID, func(q sqlc.Querier) error {
+		current, err := q.GetNoteItem(r.Context(), sqlc.GetNoteItemParams{ID: itemID, NoteID: noteID})
+		if errors.Is(err, sql.ErrNoRows) {
+			return errItemNotFound
+		}
+		if err != nil {
+			return err
+		}
+		params := sqlc.UpdateNoteItemParams{
+			ID:      itemID,
+			NoteID:  noteID,
+			Text:    current.Text,
+			Checked: current.Checked,
+			Indent:  current.Indent,
+		}
+		err = errors.Join(
+			patch.string("text", &params.Text),
+			patch.bool("checked", &params.Checked),
+			patch.int32("indent", &params.Indent),
+		)
+		if err == nil {
+			err = validateItem(params.Text, params.Indent)
+		}
+		if err != nil {
+			return err
+		}
+		item, err = q.UpdateNoteItem(r.Context(), params)
+		return err
+	})
+	if err != nil {
+		writeItemError(w, r, err, "Error al actualizar el ítem")
+		return
+	}
+
+	w.Header().Set("ETag", etag(note.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newNoteItemDTO(item))
+}
+
+func (h *UserHandler) deleteNoteItem(w http.ResponseWriter, r *http.Request) {
+	noteID, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	itemID, ok := pathID(w, r, "item", "ID de ítem inválido")
+	if !ok {
+		return
+	}
+
+	note, err := h.changeItems(r.Context(), currentUserID(r), noteID, func(q sqlc.Querier) error {
+		deleted, err := q.DeleteNoteItem(r.Context(), sqlc.DeleteNoteItemParams{ID: itemID, NoteID: noteID})
+		if err == nil && deleted == 0 {
+			return errItemNotFound
+		}
+		return err
+	})
+	if err != nil {
+		writeItemError(w, r, err, "Error al borrar el ítem")
+		return
+	}
+	w.Header().Set("ETag", etag(note.Version))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reorderNoteItems recibe {"item_ids": [...]} con todos los ítems de la
+// nota en el orden nuevo.
+func (h *UserHandler) reorderNoteItems(w http.ResponseWriter, r *http.Request) {
+	noteID, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	var input struct {
+		ItemIDs []int32 `json:"item_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeInvalidJSON(w, r)
+		return
+	}
+
+	var items []sqlc.NoteItem
+	note, err := h.changeItems(r.Context(), currentUserID(r), noteID, func(q sqlc.Querier) error {
+		current, err := q.ListNoteItems(r.Context(), noteID)
+		if err != nil {
+			return err
+		}
+		ids := make([]int32, len(current))
+		for i, item := range current {
+			ids[i] = item.ID
+		}
+		slices.Sort(ids)
+		sorted := slices.Sorted(slices.Values(input.ItemIDs))
+		if !slices.Equal(ids, sorted) {
+			return fieldError{"item_ids", "item_ids tiene que tener cada ítem de la nota una sola vez"}
+		}
+		items, err = setItemPositions(r.Context(), q, noteID, input.ItemIDs)
+		return err
+	})
+	if err != nil {
+		writeItemError(w, r, err, "Error al ordenar los ítems")
+		return
+	}
+
+	w.Header().Set("ETag", etag(note.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapSlice(items, newNoteItemDTO))
+}
+
+// moveCheckedItemsDown deja los ítems tildados al final de la lista, sin
+// cambiar el orden entre los tildados ni entre los que no lo están.
+func (h *UserHandler) moveCheckedItemsDown(w http.ResponseWriter, r *http.Request) {
+	noteID, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+
+	var items []sqlc.NoteItem
+	note, err := h.changeItems(r.Context(), currentUserID(r), noteID, func(q sqlc.Querier) error {
+		current, err := q.ListNoteItems(r.Context(), noteID)
+		if err != nil {
+			return err
+		}
+		slices.SortStableFunc(current, func(a, b sqlc.NoteItem) int {
+			switch {
+			case a.Checked == b.Checked:
+				return 0
+			case b.Checked:
+				return -1
+			default:
+				return 1
+			}
+		})
+		ids := make([]int32, len(current))
+		for i, item := range current {
+			ids[i] = item.ID
+		}
+		items, err = setItemPositions(r.Context(), q, noteID, ids)
+		return err
+	})
+	if err != nil {
+		writeItemError(w, r, err, "Error al ordenar los ítems")
+		return
+	}
+
+	w.Header().Set("ETag", etag(note.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapSlice(items, newNoteItemDTO))
+}
+
+// convertNote pasa una nota de texto a checklist, con un ítem por línea, o
+// una checklist a texto, dejando la lista de tareas en el cuerpo. Como
+// reemplaza el contenido entero, pide If-Match.
+func (h *UserHandler) convertNote(w http.ResponseWriter, r *http.Request) {
+	noteID, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	ifMatch, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+	var input struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeInvalidJSON(w, r)
+		return
+	}
+	if _, err := parseKind(&input.Kind); err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	userID := currentUserID(r)
+	var note sqlc.GetNoteRow
+	err := h.store.InTx(r.Context(), func(q sqlc.Querier) error {
+		current, err := q.GetNote(r.Context(), sqlc.GetNoteParams{ID: noteID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		if !ifMatch.matches(current.Version) {
+			return errStaleVersion
+		}
+		if current.Kind == input.Kind {
+			note = current
+			return nil
+		}
+
+		params := sqlc.SetNoteContentParams{
+			ID:         noteID,
+			UserID:     userID,
+			Kind:       input.Kind,
+			Body:       current.Body,
+			BodyFormat: current.BodyFormat,
+			Version:    sql.NullInt32{Int32: current.Version, Valid: true},
+		}
+		if input.Kind == kindChecklist {
+			items := checklist.Parse(current.Body.String)
+			if err := createNoteItems(r.Context(), q, noteID, items); err != nil {
+				return err
+			}
+			params.Body = sql.NullString{String: checklist.Format(items), Valid: true}
+			params.BodyFormat = render.Markdown
+			// El texto original queda como revisión
+			if err := h.saveNoteRevision(r.Context(), q, userID, noteID, current.Title, params.Body, params.BodyFormat); err != nil {
+				return err
+			}
+		} else if err := q.DeleteNoteItems(r.Context(), noteID); err != nil {
+			return err
+		}
+
+		updated, err := q.SetNoteContent(r.Context(), params)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errStaleVersion
+		}
+		note = sqlc.GetNoteRow(updated)
+		return err
+	})
+	if errors.Is(err, errStaleVersion) {
+		h.writeStaleNote(w, r, noteID)
+		return
+	}
+	if err != nil {
+		writeItemError(w, r, err, "Error al convertir la nota")
+		return
+	}
+
+	w.Header().Set("ETag", etag(note.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newNoteDTO(note))
+}
+
+// changeItems corre fn en una transacción con la nota bloqueada y después
+// vuelve a armar el cuerpo con los ítems, lo que también sube la versión de
+// la nota. Devuelve la nota actualizada.
+func (h *UserHandler) changeItems(ctx context.Context, userID, noteID int32, fn func(q sqlc.Querier) error) (sqlc.SetNoteContentRow, error) {
+	var note sqlc.SetNoteContentRow
+	err := h.store.InTx(ctx, func(q sqlc.Querier) error {
+		kind, err := q.LockNote(ctx, sqlc.LockNoteParams{ID: noteID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		if kind != kindChecklist {
+			return errNotChecklist
+		}
+		if err := fn(q); err != nil {
+			return err
+		}
+
+		items, err := q.ListNoteItems(ctx, noteID)
+		if err != nil {
+			return err
+		}
+		note, err = q.SetNoteContent(ctx, sqlc.SetNoteContentParams{
+			ID:         noteID,
+			UserID:     userID,
+			Kind:       kindChecklist,
+			Body:       sql.NullString{String: checklist.Format(checklistItems(items)), Valid: true},
+			BodyFormat: render.Markdown,
+		})
+		return err
+	})
+	return note, err
+}
+
+// createNoteItems agrega items a una nota que todavía no tiene ítems.
+func createNoteItems(ctx context.Context, q sqlc.Querier, noteID int32, items []checklist.Item) error {
+	for i, item := range items {
+		_, err := q.CreateNoteItem(ctx, sqlc.CreateNoteItemParams{
+			NoteID:   noteID,
+			Text:     item.Text,
+			Checked:  item.Checked,
+			Position: int32(i),
+			Indent:   item.Indent,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setItemPositions numera los ítems en el orden de ids y devuelve la lista
+// nueva.
+func setItemPositions(ctx context.Context, q sqlc.Querier, noteID int32, ids []int32) ([]sqlc.NoteItem, error) {
+	for position, id := range ids {
+		_, err := q.SetNoteItemPosition(ctx, sqlc.SetNoteItemPositionParams{ID: id, NoteID: noteID, Position: int32(position)})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return q.ListNoteItems(ctx, noteID)
+}
+
+func checklistItems(items []sqlc.NoteItem) []checklist.Item {
+	result := make([]checklist.Item, len(items))
+	for i, item := range items {
+		result[i] = checklist.Item{Text: item.Text, Checked: item.Checked, Indent: item.Indent}
+	}
+	return result
+}
+
+func writeItemError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeNotFound(w, r, "Nota no encontrada")
+	case errors.Is(err, errItemNotFound):
+		writeNotFound(w, r, "Ítem no encontrado")
+	case errors.Is(err, errNotChecklist):
+		writeProblem(w, r, http.StatusConflict, codeConflict, "La nota no es una checklist; convertila con POST /api/v1/notes/{id}/convert")
+	case len(fieldErrors(err)) > 0:
+		writeBadRequest(w, r, err)
+	default:
+		writeInternalError(w, r, err, fallback)
+	}
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func itemPath(noteID, itemID int32) string {
+	return path("/api/v1/notes", noteID) + "/items/" + strconv.Itoa(int(itemID))
+}
+
+func listItems(c *client, noteID int32) []noteItemDTO {
+	c.t.Helper()
+	var items []noteItemDTO
+	rec := c.do("GET", path("/api/v1/notes", noteID)+"/items", nil)
+	expectStatus(c.t, rec, http.StatusOK)
+	decode(c.t, rec, &items)
+	return items
+}
+
+func itemTexts(items []noteItemDTO) []string {
+	texts := make([]string, len(items))
+	for i, item := range items {
+		texts[i] = item.Text
+	}
+	return texts
+}
+
+func TestChecklistItems(t *testing.T) {
+	h, _ := newTestAPI(t)
+	c := newClient(t, h)
+	c.signUp("ana")
+
+	note := createNote(c, map[string]any{"title": "Compras", "kind": "checklist", "body": "pan\n- [x] leche\n  huevos"})
+	if note.Kind != "checklist" || note.BodyFormat != "markdown" || *note.Body != "- [ ] pan\n- [x] leche\n  - [ ] huevos\n" {
+		t.Fatalf("checklist creada = %+v, body %q", note, *note.Body)
+	}
+	items := listItems(c, note.ID)
+	if len(items) != 3 || !items[1].Checked || items[2].Indent != 1 {
+		t.Fatalf("ítems = %+v", items)
+	}
+
+	rec := c.do("POST", path("/api/v1/notes", note.ID)+"/items", map[string]any{"text": "café", "position": 0})
+	expectStatus(t, rec, http.StatusCreated)
+	var cafe noteItemDTO
+	decode(t, rec, &cafe)
+	if got := rec.Header().Get("ETag"); got != `"2"` {
+		t.Fatalf("ETag = %s", got)
+	}
+	if got := itemTexts(listItems(c, note.ID)); len(got) != 4 || got[0] != "café" || got[1] != "pan" {
+		t.Fatalf("ítems después de insertar = %v", got)
+	}
+
+	rec = c.do("PATCH", itemPath(note.ID, cafe.ID), `{"checked": true}`, "Content-Type", "application/merge-patch+json")
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &cafe)
+	if !cafe.Checked || cafe.Text != "café" {
+		t.Fatalf("ítem tildado = %+v", cafe)
+	}
+
+	var sorted []noteItemDTO
+	rec = c.do("POST", path("/api/v1/notes", note.ID)+"/items/move-checked-down", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &sorted)
+	if got := itemTexts(sorted); got[0] != "pan" || got[1] != "huevos" || got[2] != "café" || got[3] != "leche" {
+		t.Fatalf("ítems con los tildados abajo = %v", got)
+	}
+
+	ids := []int32{sorted[3].ID, sorted[2].ID, sorted[1].ID, sorted[0].ID}
+	rec = c.do("PUT", path("/api/v1/notes", note.ID)+"/items/order", map[string]any{"item_ids": ids})
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &sorted)
+	if sorted[0].Text != "leche" || sorted[3].Text != "pan" {
+		t.Fatalf("ítems reordenados = %v", itemTexts(sorted))
+	}
+	rec = c.do("PUT", path("/api/v1/notes", note.ID)+"/items/order", map[string]any{"item_ids": ids[:3]})
+	expectProblem(t, rec, http.StatusBadRequest, codeValidationFailed)
+
+	rec = c.do("DELETE", itemPath(note.ID, sorted[0].ID), nil)
+	expectStatus(t, rec, http.StatusNoContent)
+	rec = c.do("DELETE", itemPath(note.ID, sorted[0].ID), nil)
+	expectProblem(t, rec, http.StatusNotFound, codeNotFound)
+
+	// El cuerpo sigue a los ítems y no se puede editar directamente
+	var current noteWithTagsDTO
+	rec = c.do("GET", path("/api/v1/notes", note.ID), nil)
+	decode(t, rec, &current)
+	if len(current.Items) != 3 || *current.Body != "- [x] café\n  - [ ] huevos\n- [ ] pan\n" {
+		t.Fatalf("checklist = %+v, body %q", current, *current.Body)
+	}
+	rec = c.do("PATCH", path("/api/v1/notes", note.ID), `{"body": "otra cosa"}`, "If-Match", etag(current.Version), "Content-Type", "application/merge-patch+json")
+	expectProblem(t, rec, http.StatusBadRequest, codeValidationFailed)
+
+	// PUT con la nota tal como vino solo cambia el título; body y body_format
+	// se ignoran
+	folder := createFolder(c, "Súper", nil)
+	rec = c.do("PUT", path("/api/v1/notes", note.ID), map[string]any{
+		"title": "Compras del mes", "body": "otra cosa", "body_format": "plain", "folder_id": folder.ID,
+	}, "If-Match", etag(current.Version))
+	expectStatus(t, rec, http.StatusOK)
+	var renamed noteDTO
+	decode(t, rec, &renamed)
+	if renamed.Title != "Compras del mes" || *renamed.Body != *current.Body || renamed.BodyFormat != "markdown" ||
+		renamed.FolderID == nil || *renamed.FolderID != folder.ID || renamed.Kind != "checklist" {
+		t.Fatalf("checklist con PUT = %+v, body %q", renamed, *renamed.Body)
+	}
+	if got := listItems(c, note.ID); len(got) != 3 {
+		t.Fatalf("ítems después del PUT = %+v", got)
+	}
+
+	rec = c.do("POST", path("/api/v1/notes", note.ID)+"/items", map[string]any{"text": "dos\nlíneas", "indent": 2})
+	p := expectProblem(t, rec, http.StatusBadRequest, codeValidationFailed)
+	if len(p.Errors) != 2 {
+		t.Fatalf("errors = %+v", p.Errors)
+	}
+
+	text := createNote(c, map[string]any{"title": "Texto"})
+	rec = c.do("POST", path("/api/v1/notes", text.ID)+"/items", map[string]any{"text": "x"})
+	expectProblem(t, rec, http.StatusConflict, codeConflict)
+}
+
+func TestConvertNote(t *testing.T) {
+	h, _ := newTestAPI(t)
+	c := newClient(t, h)
+	c.signUp("ana")
+
+	note := createNote(c, map[string]any{"title": "Viaje", "body": "pasaporte\n\n[x] pasajes\n"})
+	notePath := path("/api/v1/notes", note.ID)
+
+	rec := c.do("POST", notePath+"/convert", map[string]any{"kind": "checklist"})
+	expectProblem(t, rec, http.StatusPreconditionRequired, codeIfMatchRequired)
+	rec = c.do("POST", notePath+"/convert", map[string]any{"kind": "lista"}, "If-Match", `"1"`)
+	expectProblem(t, rec, http.StatusBadRequest, codeValidationFailed)
+
+	rec = c.do("POST", notePath+"/convert", map[string]any{"kind": "checklist"}, "If-Match", `"1"`)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &note)
+	if note.Kind != "checklist" || note.Version != 2 || *note.Body != "- [ ] pasaporte\n- [x] pasajes\n" {
+		t.Fatalf("nota convertida = %+v, body %q", note, *note.Body)
+	}
+	if items := listItems(c, note.ID); len(items) != 2 || !items[1].Checked {
+		t.Fatalf("ítems = %+v", items)
+	}
+
+	// El texto original queda como revisión, pero no se restaura sobre una checklist
+	var revisions []revisionDTO
+	rec = c.do("GET", notePath+"/revisions", nil)
+	decode(t, rec, &revisions)
+	if len(revisions) != 1 {
+		t.Fatalf("revisiones = %+v", revisions)
+	}
+	rec = c.do("POST", notePath+"/revisions/"+strconv.Itoa(int(revisions[0].ID))+"/restore", nil)
+	expectProblem(t, rec, http.StatusConflict, codeConflict)
+
+	rec = c.do("POST", notePath+"/convert", map[string]any{"kind": "text"}, "If-Match", `"1"`)
+	expectStatus(t, rec, http.StatusPreconditionFailed)
+
+	rec = c.do("POST", notePath+"/convert", map[string]any{"kind": "text"}, "If-Match", `"2"`)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &note)
+	if note.Kind != "text" || *note.Body != "- [ ] pasaporte\n- [x] pasajes\n" {
+		t.Fatalf("nota de vuelta a texto = %+v", note)
+	}
+	if items := listItems(c, note.ID); len(items) != 0 {
+		t.Fatalf("ítems después de convertir a texto = %+v", items)
+	}
+
+	// Y de nuevo a checklist sin perder los tildados
+	rec = c.do("POST", notePath+"/convert", map[string]any{"kind": "checklist"}, "If-Match", etag(note.Version))
+	expectStatus(t, rec, http.StatusOK)
+	if items := listItems(c, note.ID); len(items) != 2 || items[0].Checked || !items[1].Checked {
+		t.Fatalf("ítems = %+v", items)
+	}
+}
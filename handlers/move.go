@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	sqlc "tpeweb.com/servidor-go/db/sqlc"
+)
+
+var (
+	errFolderNotFound = errors.New("carpeta no encontrada")
+	errParentNotFound = errors.New("carpeta destino no encontrada")
+	errFolderCycle    = errors.New("la carpeta destino es la misma carpeta o una de sus subcarpetas")
+)
+
+// moveFolderHandler atiende POST /api/v1/folders/{id}/move con el body
+// {"parent_folder_id": 5} o {"parent_folder_id": null} para moverla a la raíz.
+func (h *UserHandler) moveFolderHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ParentFolderID *int32 `json:"parent_folder_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeInvalidJSON(w, r)
+		return
+	}
+
+	parentID := sql.NullInt32{Valid: false}
+	if input.ParentFolderID != nil {
+		parentID = sql.NullInt32{Int32: *input.ParentFolderID, Valid: true}
+	}
+
+	userID := currentUserID(r)
+	var folder sqlc.Folder
+	var err error
+	err = h.store.InTx(r.Context(), func(q sqlc.Querier) error {
+		if err := checkFolderMove(r.Context(), q, userID, id, parentID); err != nil {
+			return err
+		}
+		if _, err := q.MoveFolder(r.Context(), sqlc.MoveFolderParams{
+			ID:             id,
+			UserID:         userID,
+			ParentFolderID: parentID,
+		}); err != nil {
+			return err
+		}
+		folder, err = q.GetFolder(r.Context(), sqlc.GetFolderParams{ID: id, UserID: userID})
+		return err
+	})
+	if err != nil {
+		writeMoveError(w, r, err, "Error al mover la carpeta")
+		return
+	}
+
+	w.Header().Set("ETag", etag(folder.Version))
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(newFolderDTO(folder))
+	if err != nil {
+		writeInternalError(w, r, err, "Error al codificar JSON")
+		return
+	}
+}
+
+// checkFolderMove serializa los movimientos de carpetas del usuario, bloquea
+// la carpeta y su destino dentro de la transacción y valida que el destino
+// exista, sea del usuario y no genere un ciclo.
+// Como parent_folder_id arrastra a toda la subcarpeta, mover la raíz del
+// subárbol alcanza para mover todo su contenido.
+func checkFolderMove(ctx context.Context, q sqlc.Querier, userID, folderID int32, parentID sql.NullInt32) error {
+	// Sin esto, mover A dentro de B y B dentro de A a la vez (o sobre
+	// subcarpetas de cada una) pasa los dos chequeos y deja un ciclo
+	if err := q.LockFolderTree(ctx, userID); err != nil {
+		return err
+	}
+
+	ids := []int32{folderID}
+	if parentID.Valid {
+		if parentID.Int32 == folderID {
+			return errFolderCycle
+		}
+		ids = append(ids, parentID.Int32)
+	}
+
+	locked, err := q.LockFolders(ctx, sqlc.LockFoldersParams{UserID: userID, FolderIds: ids})
+	if err != nil {
+		return err
+	}
+	found := make(map[int32]bool, len(locked))
+	for _, id := range locked {
+		found[id] = true
+	}
+	if !found[folderID] {
+		return errFolderNotFound
+	}
+	if !parentID.Valid {
+		return nil
+	}
+	if !found[parentID.Int32] {
+		return errParentNotFound
+	}
+
+	cycle, err := q.IsFolderDescendant(ctx, sqlc.IsFolderDescendantParams{
+		FolderID:    folderID,
+		CandidateID: parentID.Int32,
+	})
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return errFolderCycle
+	}
+	return nil
+}
+
+func writeMoveError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	switch {
+	case errors.Is(err, errFolderNotFound), errors.Is(err, sql.ErrNoRows):
+		writeNotFound(w, r, "No encontrado")
+	case errors.Is(err, errParentNotFound):
+		writeNotFound(w, r, "Carpeta destino no encontrada")
+	case errors.Is(err, errFolderCycle):
+		writeProblem(w, r, http.StatusConflict, codeFolderCycle, "No se puede mover una carpeta dentro de sí misma o de una subcarpeta")
+	default:
+		writeInternalError(w, r, err, fallback)
+	}
+}
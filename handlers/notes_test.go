@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func createNote(c *client, body map[string]any) noteDTO {
+	c.t.Helper()
+	rec := c.do("POST", "/api/v1/notes", body)
+	expectStatus(c.t, rec, http.StatusCreated)
+	var note noteDTO
+	decode(c.t, rec, &note)
+	return note
+}
+
+func TestNoteLifecycle(t *testing.T) {
+	h, _ := newTestAPI(t)
+	c := newClient(t, h)
+	c.signUp("ana")
+
+	note := createNote(c, map[string]any{"title": "Compras", "body": "pan"})
+	if note.Version != 1 || note.Body == nil || *note.Body != "pan" {
+		t.Fatalf("nota creada = %+v", note)
+	}
+	notePath := path("/api/v1/notes", note.ID)
+
+	rec := c.do("GET", notePath, nil)
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("ETag"); got != `"1"` {
+		t.Fatalf("ETag = %s", got)
+	}
+
+	rec = c.do("PUT", notePath, map[string]any{"title": "Compras", "body": "pan y leche"})
+	expectProblem(t, rec, http.StatusPreconditionRequired, codeIfMatchRequired)
+
+	rec = c.do("PUT", notePath, map[string]any{"title": "Compras", "body": "pan y leche"}, "If-Match", `"1"`)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &note)
+	if note.Version != 2 || *note.Body != "pan y leche" {
+		t.Fatalf("nota actualizada = %+v", note)
+	}
+
+	// Con la versión vieja responde 412 con la copia actual
+	rec = c.do("PATCH", notePath, `{"title": "Otra"}`, "If-Match", `"1"`, "Content-Type", "application/merge-patch+json")
+	expectStatus(t, rec, http.StatusPreconditionFailed)
+	if got := rec.Header().Get("ETag"); got != `"2"` {
+		t.Fatalf("ETag = %s", got)
+	}
+
+	rec = c.do("PATCH", notePath, `{"body": null}`, "If-Match", `"2"`, "Content-Type", "application/merge-patch+json")
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &note)
+	if note.Title != "Compras" || note.Body != nil {
+		t.Fatalf("nota con merge patch = %+v", note)
+	}
+
+	var revisions []revisionDTO
+	rec = c.do("GET", notePath+"/revisions", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &revisions)
+	if len(revisions) != 2 {
+		t.Fatalf("revisiones = %+v, se esperaban 2", revisions)
+	}
+
+	rec = c.do("DELETE", notePath, nil, "If-Match", `"3"`)
+	expectStatus(t, rec, http.StatusNoContent)
+	rec = c.do("GET", notePath, nil)
+	expectProblem(t, rec, http.StatusNotFound, codeNotFound)
+
+	var trash trashResponse
+	rec = c.do("GET", "/api/v1/trash", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &trash)
+	if len(trash.Notes) != 1 || trash.Notes[0].ID != note.ID {
+		t.Fatalf("papelera = %+v", trash)
+	}
+
+	rec = c.do("POST", path("/api/v1/trash/notes", note.ID)+"/restore", nil)
+	expectStatus(t, rec, http.StatusOK)
+	rec = c.do("GET", notePath, nil)
+	expectStatus(t, rec, http.StatusOK)
+
+	// El borrado definitivo solo aplica a lo que está en la papelera
+	rec = c.do("DELETE", path("/api/v1/trash/notes", note.ID), nil)
+	expectProblem(t, rec, http.StatusNotFound, codeNotFound)
+	rec = c.do("DELETE", notePath, nil, "If-Match", `"3"`)
+	expectStatus(t, rec, http.StatusNoContent)
+	rec = c.do("DELETE", path("/api/v1/trash/notes", note.ID), nil)
+	expectStatus(t, rec, http.StatusNoContent)
+	rec = c.do("POST", path("/api/v1/trash/notes", note.ID)+"/restore", nil)
+	expectProblem(t, rec, http.StatusNotFound, codeNotFound)
+}
+
+func TestNoteValidation(t *testing.T) {
+	h, _ := newTestAPI(t)
+	c := newClient(t, h)
+	c.signUp("ana")
+
+	rec := c.do("POST", "/api/v1/notes", map[string]any{"body": "sin título"})
+	p := expectProblem(t, rec, http.StatusBadRequest, codeValidationFailed)
+	if len(p.Errors) != 1 || p.Errors[0].Field != "title" {
+		t.Fatalf("errors = %+v", p.Errors)
+	}
+
+	rec = c.do("POST", "/api/v1/notes", map[string]any{"title": "x", "folder_id": 99})
+	expectProblem(t, rec, http.StatusNotFound, codeNotFound)
+
+	rec = c.do("GET", "/api/v1/notes?sort=color", nil)
+	expectProblem(t, rec, http.StatusBadRequest, codeValidationFailed)
+
+	note := createNote(c, map[string]any{"title": "x"})
+	rec = c.do("PATCH", path("/api/v1/notes", note.ID), `{"title": "y"}`, "If-Match", `"1"`, "Content-Type", "text/plain")
+	expectProblem(t, rec, http.StatusUnsupportedMediaType, codeUnsupportedMediaType)
+}
+
+func TestNoteIfMatch(t *testing.T) {
+	h, _ := newTestAPI(t)
+	c := newClient(t, h)
+	c.signUp("ana")
+
+	note := createNote(c, map[string]any{"title": "Compras"})
+	notePath := path("/api/v1/notes", note.ID)
+
+	// If-Match compara en forma estricta: un ETag débil no es "el mismo"
+	rec := c.do("PUT", notePath, map[string]any{"title": "x"}, "If-Match", `W/"1"`)
+	expectProblem(t, rec, http.StatusBadRequest, codeInvalidRequest)
+
+	// * guarda sin mirar la versión, pero la nota tiene que existir
+	rec = c.do("PUT", notePath, map[string]any{"title": "Compras y más"}, "If-Match", "*")
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &note)
+	if note.Version != 2 || note.Title != "Compras y más" {
+		t.Fatalf("nota con If-Match: * = %+v", note)
+	}
+	rec = c.do("PUT", path("/api/v1/notes", 999), map[string]any{"title": "x"}, "If-Match", "*")
+	expectProblem(t, rec, http.StatusNotFound, codeNotFound)
+
+	rec = c.do("DELETE", notePath, nil, "If-Match", "*")
+	expectStatus(t, rec, http.StatusNoContent)
+	rec = c.do("DELETE", notePath, nil, "If-Match", "*")
+	expectProblem(t, rec, http.StatusNotFound, codeNotFound)
+}
+
+func TestNotesAreIsolatedPerUser(t *testing.T) {
+	h, _ := newTestAPI(t)
+	ana := newClient(t, h)
+	ana.signUp("ana")
+	beto := newClient(t, h)
+	beto.signUp("beto")
+
+	note := createNote(ana, map[string]any{"title": "privada"})
+
+	rec := beto.do("GET", path("/api/v1/notes", note.ID), nil)
+	expectProblem(t, rec, http.StatusNotFound, codeNotFound)
+	rec = beto.do("DELETE", path("/api/v1/notes", note.ID), nil, "If-Match", `"1"`)
+	expectProblem(t, rec, http.StatusNotFound, codeNotFound)
+
+	var page struct {
+		Items []noteDTO `json:"items"`
+	}
+	rec = beto.do("GET", "/api/v1/notes", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &page)
+	if len(page.Items) != 0 {
+		t.Fatalf("beto ve notas de ana: %+v", page.Items)
+	}
+}
+
+func TestListNotesPagination(t *testing.T) {
+	h, _ := newTestAPI(t)
+	c := newClient(t, h)
+	c.signUp("ana")
+
+	for _, title := range []string{"d", "b", "e", "a", "c"} {
+		createNote(c, map[string]any{"title": title})
+	}
+
+	var titles []string
+	next := "/api/v1/notes?sort=title&limit=2"
+	for pages := 0; next != ""; pages++ {
+		if pages > 3 {
+			t.Fatal("la paginación no termina")
+		}
+		var page struct {
+			Items      []noteDTO `json:"items"`
+			NextCursor *string   `json:"next_cursor"`
+		}
+		rec := c.do("GET", next, nil)
+		expectStatus(t, rec, http.StatusOK)
+		decode(t, rec, &page)
+		for _, note := range page.Items {
+			titles = append(titles, note.Title)
+		}
+		next = ""
+		if page.NextCursor != nil {
+			next = "/api/v1/notes?sort=title&limit=2&cursor=" + url.QueryEscape(*page.NextCursor)
+		}
+	}
+	if got := len(titles); got != 5 {
+		t.Fatalf("títulos = %v", titles)
+	}
+	for i, want := range []string{"a", "b", "c", "d", "e"} {
+		if titles[i] != want {
+			t.Fatalf("títulos = %v, no están en orden", titles)
+		}
+	}
+}
+
+func TestNoteTags(t *testing.T) {
+	h, _ := newTestAPI(t)
+	c := newClient(t, h)
+	c.signUp("ana")
+
+	first := createNote(c, map[string]any{"title": "uno"})
+	second := createNote(c, map[string]any{"title": "dos"})
+	for _, id := range []int32{first.ID, second.ID} {
+		rec := c.do("POST", path("/api/v1/notes", id)+"/tags", map[string]any{"name": "casa"})
+		expectStatus(t, rec, http.StatusOK)
+	}
+	rec := c.do("POST", path("/api/v1/notes", second.ID)+"/tags", map[string]any{"name": "urgente"})
+	expectStatus(t, rec, http.StatusOK)
+
+	var page struct {
+		Items []noteDTO `json:"items"`
+	}
+	rec = c.do("GET", "/api/v1/notes?tag=casa&tag=urgente", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &page)
+	if len(page.Items) != 1 || page.Items[0].ID != second.ID {
+		t.Fatalf("notas con casa y urgente = %+v", page.Items)
+	}
+
+	rec = c.do("POST", "/api/v1/tags", map[string]any{"name": "casa"})
+	expectProblem(t, rec, http.StatusConflict, codeAlreadyExists)
+
+	var tags []tagWithCountDTO
+	rec = c.do("GET", "/api/v1/tags", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &tags)
+	if len(tags) != 2 || tags[0].Name != "casa" || tags[0].NoteCount != 2 {
+		t.Fatalf("etiquetas = %+v", tags)
+	}
+
+	// Borrar la etiqueta la saca de las notas
+	rec = c.do("DELETE", path("/api/v1/tags", tags[0].ID), nil)
+	expectStatus(t, rec, http.StatusNoContent)
+	var withTags noteWithTagsDTO
+	rec = c.do("GET", path("/api/v1/notes", second.ID), nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &withTags)
+	if len(withTags.Tags) != 1 || withTags.Tags[0].Name != "urgente" {
+		t.Fatalf("etiquetas de la nota = %+v", withTags.Tags)
+	}
+}
+
+func TestSearchNotes(t *testing.T) {
+	h, _ := newTestAPI(t)
+	c := newClient(t, h)
+	c.signUp("ana")
+
+	match := createNote(c, map[string]any{"title": "Receta de pizza", "body": "harina, agua y levadura"})
+	createNote(c, map[string]any{"title": "Receta de pan", "body": "harina integral"})
+	createNote(c, map[string]any{"title": "Lista", "body": "nada que ver"})
+
+	var results []searchResult
+	rec := c.do("GET", "/api/v1/notes/search?q="+url.QueryEscape("receta -pan levad*"), nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &results)
+	if len(results) != 1 || results[0].ID != match.ID {
+		t.Fatalf("resultados = %+v", results)
+	}
+	if results[0].TitleHighlight != "<mark>Receta</mark> de pizza" {
+		t.Fatalf("title_highlight = %q", results[0].TitleHighlight)
+	}
+
+	rec = c.do("GET", "/api/v1/notes/search?q=-pan", nil)
+	expectProblem(t, rec, http.StatusBadRequest, codeValidationFailed)
+}
+
+func TestNoteMarkdown(t *testing.T) {
+	h, _ := newTestAPI(t)
+	c := newClient(t, h)
+	c.signUp("ana")
+
+	plain := createNote(c, map[string]any{"title": "Vieja", "body": "<b>hola</b>\nchau"})
+	if plain.BodyFormat != "plain" {
+		t.Fatalf("body_format por defecto = %q", plain.BodyFormat)
+	}
+	rec := c.do("GET", path("/api/v1/notes", plain.ID)+"?format=html", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Body.String(); !strings.Contains(got, "&lt;b&gt;hola&lt;/b&gt;<br>") {
+		t.Fatalf("texto plano en HTML = %q", got)
+	}
+
+	body := "# Lista\n\n- [x] **pan**\n- [ ] ~~leche~~\n\n<script>alert(1)</script>\n\n[a](javascript:alert(1)) [b](https://example.com) ![img](https://example.com/x.png)\n"
+	note := createNote(c, map[string]any{"title": "Compras", "body": body, "body_format": "markdown"})
+	rec = c.do("GET", path("/api/v1/notes", note.ID)+"?format=html", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Fatalf("Content-Type = %s", got)
+	}
+	if got := rec.Header().Get("ETag"); got != `"1"` {
+		t.Fatalf("ETag = %s", got)
+	}
+	html := rec.Body.String()
+	for _, want := range []string{"<h1>Lista</h1>", `<input checked="" disabled="" type="checkbox"> <strong>pan</strong>`, "<del>leche</del>", `<a href="https://example.com" rel="nofollow">b</a>`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("falta %q en %q", want, html)
+		}
+	}
+	for _, unwanted := range []string{"<script", "javascript:", "<img"} {
+		if strings.Contains(html, unwanted) {
+			t.Errorf("sobra %q en %q", unwanted, html)
+		}
+	}
+
+	rec = c.do("GET", path("/api/v1/notes", note.ID)+"?format=pdf", nil)
+	expectProblem(t, rec, http.StatusBadRequest, codeValidationFailed)
+	rec = c.do("POST", "/api/v1/notes", map[string]any{"title": "x", "body_format": "rtf"})
+	p := expectProblem(t, rec, http.StatusBadRequest, codeValidationFailed)
+	if len(p.Errors) != 1 || p.Errors[0].Field != "body_format" {
+		t.Fatalf("errors = %+v", p.Errors)
+	}
+
+	// Pasar a texto plano guarda una revisión, y restaurarla vuelve a markdown
+	rec = c.do("PATCH", path("/api/v1/notes", note.ID), `{"body_format": "plain"}`, "If-Match", `"1"`, "Content-Type", "application/merge-patch+json")
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &note)
+	if note.BodyFormat != "plain" {
+		t.Fatalf("nota con merge patch = %+v", note)
+	}
+	var revisions []revisionDTO
+	rec = c.do("GET", path("/api/v1/notes", note.ID)+"/revisions", nil)
+	decode(t, rec, &revisions)
+	if len(revisions) != 1 {
+		t.Fatalf("revisiones = %+v, se esperaba 1", revisions)
+	}
+	rec = c.do("POST", path("/api/v1/notes", note.ID)+"/revisions/"+strconv.Itoa(int(revisions[0].ID))+"/restore", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &note)
+	if note.BodyFormat != "markdown" {
+		t.Fatalf("nota restaurada = %+v", note)
+	}
+}
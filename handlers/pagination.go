@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// page es el sobre que devuelven los listados paginados. NextCursor es null
+// cuando no hay más resultados.
+type page[T any] struct {
+	Items      []T     `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// pageCursor identifica la última fila devuelta. Guarda también el orden
+// pedido para rechazar cursores usados con otro sort/order.
+type pageCursor struct {
+	SortBy     string `json:"s"`
+	Descending bool   `json:"d"`
+	Value      string `json:"v"`
+	ID         int32  `json:"id"`
+}
+
+type pageRequest struct {
+	Limit      int
+	SortBy     string
+	Descending bool
+	Cursor     *pageCursor
+}
+
+// parsePageRequest lee limit, cursor, sort y order de la query string.
+// sortFields son las columnas permitidas; la primera es el orden por defecto.
+func parsePageRequest(r *http.Request, sortFields ...string) (pageRequest, error) {
+	query := r.URL.Query()
+	req := pageRequest{Limit: defaultPageSize, SortBy: sortFields[0]}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return req, fieldError{"limit", "limit debe estar entre 1 y " + strconv.Itoa(maxPageSize)}
+		}
+		req.Limit = limit
+	}
+
+	if sortBy := query.Get("sort"); sortBy != "" {
+		if !slices.Contains(sortFields, sortBy) {
+			return req, fieldError{"sort", "sort debe ser uno de: " + strings.Join(sortFields, ", ")}
+		}
+		req.SortBy = sortBy
+	}
+
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		req.Descending = true
+	default:
+		return req, fieldError{"order", "order debe ser asc o desc"}
+	}
+
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(cursorStr)
+		if err != nil {
+			return req, fieldError{"cursor", "cursor inválido"}
+		}
+		var cursor pageCursor
+		if err := json.Unmarshal(raw, &cursor); err != nil {
+			return req, fieldError{"cursor", "cursor inválido"}
+		}
+		if cursor.SortBy != req.SortBy || cursor.Descending != req.Descending {
+			return req, fieldError{"cursor", "el cursor corresponde a otro orden"}
+		}
+		req.Cursor = &cursor
+	}
+
+	return req, nil
+}
+
+// fetchSize pide una fila de más para saber si hay otra página.
+func (p pageRequest) fetchSize() int32 {
+	return int32(p.Limit + 1)
+}
+
+// cursorText y cursorTime devuelven el valor del cursor como lo esperan las
+// queries: texto para columnas como title o name, tiempo para created_at y
+// updated_at. El que no corresponde queda en su valor cero.
+func (p pageRequest) cursorText() string {
+	if p.Cursor == nil || isTimeSort(p.SortBy) {
+		return ""
+	}
+	return p.Cursor.Value
+}
+
+func (p pageRequest) cursorTime() (time.Time, error) {
+	if p.Cursor == nil || !isTimeSort(p.SortBy) {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, p.Cursor.Value)
+	if err != nil {
+		return time.Time{}, fieldError{"cursor", "cursor inválido"}
+	}
+	return t, nil
+}
+
+func (p pageRequest) cursorID() int32 {
+	if p.Cursor == nil {
+		return 0
+	}
+	return p.Cursor.ID
+}
+
+func isTimeSort(sortBy string) bool {
+	return sortBy == "created_at" || sortBy == "updated_at"
+}
+
+// newPage recorta la fila extra y arma el cursor a partir de la última fila.
+// key devuelve el valor de la columna de orden y el id de una fila.
+func newPage[T any](req pageRequest, rows []T, key func(T) (string, int32)) page[T] {
+	result := page[T]{Items: rows}
+	if result.Items == nil {
+		result.Items = []T{}
+	}
+	if len(rows) <= req.Limit {
+		return result
+	}
+
+	result.Items = rows[:req.Limit]
+	value, id := key(result.Items[req.Limit-1])
+	raw, _ := json.Marshal(pageCursor{
+		SortBy:     req.SortBy,
+		Descending: req.Descending,
+		Value:      value,
+		ID:         id,
+	})
+	next := base64.RawURLEncoding.EncodeToString(raw)
+	result.NextCursor = &next
+	return result
+}
+
+func formatCursorTime(t sql.NullTime) string {
+	return t.Time.Format(time.RFC3339Nano)
+}
+
+// parseTimeFilter lee un filtro opcional en formato RFC 3339.
+func parseTimeFilter(r *http.Request, name string) (sql.NullTime, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return sql.NullTime{Valid: false}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return sql.NullTime{}, fieldError{name, name + " debe tener formato RFC 3339"}
+	}
+	return sql.NullTime{Time: t, Valid: true}, nil
+}
+
+// parseIDFilter lee un filtro opcional con un ID numérico.
+func parseIDFilter(r *http.Request, name string) (sql.NullInt32, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return sql.NullInt32{Valid: false}, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return sql.NullInt32{}, fieldError{name, name + " inválido"}
+	}
+	return sql.NullInt32{Int32: int32(id), Valid: true}, nil
+}
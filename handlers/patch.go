@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"mime"
+	"net/http"
+)
+
+// mergePatch es un documento JSON Merge Patch (RFC 7396). Cada campo puede
+// faltar (no se toca), venir en null (se borra) o traer un valor nuevo.
+type mergePatch map[string]json.RawMessage
+
+// decodeMergePatch lee el body de un PATCH. Acepta application/merge-patch+json
+// y application/json. Si falla, ya escribió la respuesta.
+func decodeMergePatch(w http.ResponseWriter, r *http.Request) (mergePatch, bool) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+		writeProblem(w, r, http.StatusUnsupportedMediaType, codeUnsupportedMediaType, "PATCH requiere Content-Type application/merge-patch+json")
+		return nil, false
+	}
+
+	// Un patch que no es un objeto reemplazaría el recurso entero, para eso
+	// está PUT
+	var patch mergePatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil || patch == nil {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidJSON, "El patch tiene que ser un objeto JSON")
+		return nil, false
+	}
+	return patch, true
+}
+
+func (p mergePatch) isNull(name string) bool {
+	return string(p[name]) == "null"
+}
+
+// string aplica un campo obligatorio: no se puede borrar con null.
+func (p mergePatch) string(name string, dst *string) error {
+	raw, ok := p[name]
+	if !ok {
+		return nil
+	}
+	if p.isNull(name) {
+		return fieldError{name, name + " no puede ser null"}
+	}
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return fieldError{name, name + " tiene que ser un texto"}
+	}
+	return nil
+}
+
+// nullString aplica un campo de texto opcional; null lo deja en NULL.
+func (p mergePatch) nullString(name string, dst *sql.NullString) error {
+	raw, ok := p[name]
+	if !ok {
+		return nil
+	}
+	if p.isNull(name) {
+		*dst = sql.NullString{Valid: false}
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return fieldError{name, name + " tiene que ser un texto o null"}
+	}
+	*dst = sql.NullString{String: value, Valid: true}
+	return nil
+}
+
+// nullInt32 aplica un ID opcional; null lo deja en NULL.
+func (p mergePatch) nullInt32(name string, dst *sql.NullInt32) error {
+	raw, ok := p[name]
+	if !ok {
+		return nil
+	}
+	if p.isNull(name) {
+		*dst = sql.NullInt32{Valid: false}
+		return nil
+	}
+	var value int32
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return fieldError{name, name + " tiene que ser un número o null"}
+	}
+	*dst = sql.NullInt32{Int32: value, Valid: true}
+	return nil
+}
+
+// bool aplica un campo booleano obligatorio.
+func (p mergePatch) bool(name string, dst *bool) error {
+	raw, ok := p[name]
+	if !ok {
+		return nil
+	}
+	if p.isNull(name) {
+		return fieldError{name, name + " no puede ser null"}
+	}
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return fieldError{name, name + " tiene que ser true o false"}
+	}
+	return nil
+}
+
+// int32 aplica un campo numérico obligatorio.
+func (p mergePatch) int32(name string, dst *int32) error {
+	raw, ok := p[name]
+	if !ok {
+		return nil
+	}
+	if p.isNull(name) {
+		return fieldError{name, name + " no puede ser null"}
+	}
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return fieldError{name, name + " tiene que ser un número"}
+	}
+	return nil
+}
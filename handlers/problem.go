@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// Códigos de error estables. Los clientes tienen que decidir en base a code,
+// nunca en base al texto de detail, que puede cambiar.
+const (
+	codeInvalidRequest       = "invalid_request"
+	codeInvalidJSON          = "invalid_json"
+	codeInvalidID            = "invalid_id"
+	codeValidationFailed     = "validation_failed"
+	codeUnauthenticated      = "unauthenticated"
+	codeInvalidCredentials   = "invalid_credentials"
+	codeForbidden            = "forbidden"
+	codeNotFound             = "not_found"
+	codeMethodNotAllowed     = "method_not_allowed"
+	codeConflict             = "conflict"
+	codeAlreadyExists        = "already_exists"
+	codeFolderCycle          = "folder_cycle"
+	codeVersionMismatch      = "version_mismatch"
+	codeIfMatchRequired      = "if_match_required"
+	codeUnsupportedMediaType = "unsupported_media_type"
+	codeDiffTooLarge         = "diff_too_large"
+	codeInternal             = "internal_error"
+)
+
+// problem es el cuerpo de todas las respuestas de error (RFC 7807,
+// application/problem+json), con code y request_id como extensiones.
+type problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []fieldError `json:"errors,omitempty"`
+}
+
+// fieldError es un error de validación de un campo del body o de un
+// parámetro de la query string. También se usa como error común y corriente
+// para que los helpers de parseo puedan devolverlo.
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e fieldError) Error() string {
+	return e.Message
+}
+
+// writeProblem escribe un error en formato problem+json.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string, fields ...fieldError) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(problem{
+		Type:      "/errors/" + code,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: requestIDFrom(r.Context()),
+		Errors:    fields,
+	})
+}
+
+// writeInternalError registra el error real con el request ID y responde 500
+// solo con detail, sin mostrar errores de la base al cliente.
+func writeInternalError(w http.ResponseWriter, r *http.Request, err error, detail string) {
+	slog.Error(detail, "request_id", requestIDFrom(r.Context()), "method", r.Method, "path", r.URL.Path, "err", err)
+	writeProblem(w, r, http.StatusInternalServerError, codeInternal, detail)
+}
+
+// writeBadRequest responde 400 a partir del error de un helper de parseo. Si
+// el error trae fieldError (uno o varios unidos con errors.Join) la respuesta
+// los lista en errors.
+func writeBadRequest(w http.ResponseWriter, r *http.Request, err error) {
+	fields := fieldErrors(err)
+	if len(fields) == 0 {
+		writeProblem(w, r, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	writeValidationError(w, r, fields...)
+}
+
+// writeValidationError responde 400 con los campos que no pasaron la validación.
+func writeValidationError(w http.ResponseWriter, r *http.Request, fields ...fieldError) {
+	detail := fields[0].Message
+	if len(fields) > 1 {
+		detail = "Hay campos inválidos"
+	}
+	writeProblem(w, r, http.StatusBadRequest, codeValidationFailed, detail, fields...)
+}
+
+// writeInvalidJSON responde 400 cuando el body no se puede decodificar.
+func writeInvalidJSON(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, r, http.StatusBadRequest, codeInvalidJSON, "El cuerpo no es un JSON válido")
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, r, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Método no permitido")
+}
+
+func writeNotFound(w http.ResponseWriter, r *http.Request, detail string) {
+	writeProblem(w, r, http.StatusNotFound, codeNotFound, detail)
+}
+
+func writeInvalidID(w http.ResponseWriter, r *http.Request, detail string) {
+	writeProblem(w, r, http.StatusBadRequest, codeInvalidID, detail)
+}
+
+// requiredFields recibe pares nombre, valor y devuelve un fieldError por
+// cada valor vacío (nil si están todos).
+func requiredFields(pairs ...string) []fieldError {
+	var missing []fieldError
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] == "" {
+			missing = append(missing, fieldError{pairs[i], pairs[i] + " es obligatorio"})
+		}
+	}
+	return missing
+}
+
+// fieldErrors junta los fieldError de err, recorriendo errors.Join.
+func fieldErrors(err error) []fieldError {
+	var fe fieldError
+	if errors.As(err, &fe) {
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			var fields []fieldError
+			for _, e := range joined.Unwrap() {
+				fields = append(fields, fieldErrors(e)...)
+			}
+			return fields
+		}
+		return []fieldError{fe}
+	}
+	return nil
+}
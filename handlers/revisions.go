@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	sqlc "tpeweb.com/servidor-go/db/sqlc"
+	"tpeweb.com/servidor-go/diff"
+)
+
+// revisionRef identifica un lado del diff. ID es null para el contenido
+// actual de la nota.
+type revisionRef struct {
+	ID        *int32     `json:"id"`
+	Title     string     `json:"title"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+type revisionDiff struct {
+	From  revisionRef `json:"from"`
+	To    revisionRef `json:"to"`
+	Lines []diff.Line `json:"lines"`
+}
+
+// listNoteRevisions devuelve las revisiones de la nota, de la más nueva a la
+// más vieja, sin el cuerpo.
+func (h *UserHandler) listNoteRevisions(w http.ResponseWriter, r *http.Request) {
+	noteID, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	userID := currentUserID(r)
+	if _, err := h.store.GetNote(r.Context(), sqlc.GetNoteParams{ID: noteID, UserID: userID}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeNotFound(w, r, "Nota no encontrada")
+			return
+		}
+		writeInternalError(w, r, err, "Error interno")
+		return
+	}
+
+	revisions, err := h.store.ListNoteRevisions(r.Context(), sqlc.ListNoteRevisionsParams{NoteID: noteID, UserID: userID})
+	if err != nil {
+		writeInternalError(w, r, err, "Error al listar las revisiones")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapSlice(revisions, newRevisionDTO))
+}
+
+func (h *UserHandler) getNoteRevision(w http.ResponseWriter, r *http.Request) {
+	noteID, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	revID, ok := pathID(w, r, "rev", "ID de revisión inválido")
+	if !ok {
+		return
+	}
+	revision, err := h.store.GetNoteRevision(r.Context(), sqlc.GetNoteRevisionParams{
+		ID:     revID,
+		NoteID: noteID,
+		UserID: currentUserID(r),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeNotFound(w, r, "Revisión no encontrada")
+			return
+		}
+		writeInternalError(w, r, err, "Error interno")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newRevisionDetailDTO(revision))
+}
+
+// diffNoteRevisions compara el cuerpo de dos revisiones línea por línea.
+// from es obligatorio; to puede ser otra revisión o "current" (por defecto),
+// el contenido actual de la nota.
+func (h *UserHandler) diffNoteRevisions(w http.ResponseWriter, r *http.Request) {
+	noteID, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	fromStr := r.URL.Query().Get("from")
+	if fromStr == "" {
+		writeValidationError(w, r, fieldError{"from", "El parámetro from es obligatorio"})
+		return
+	}
+	toStr := r.URL.Query().Get("to")
+	if toStr == "" {
+		toStr = "current"
+	}
+
+	from, fromBody, ok := h.loadRevisionSide(w, r, noteID, fromStr, "from")
+	if !ok {
+		return
+	}
+	to, toBody, ok := h.loadRevisionSide(w, r, noteID, toStr, "to")
+	if !ok {
+		return
+	}
+
+	lines, err := diff.Lines(fromBody, toBody)
+	if errors.Is(err, diff.ErrTooLarge) {
+		writeProblem(w, r, http.StatusUnprocessableEntity, codeDiffTooLarge,
+			"Las versiones difieren en más de "+strconv.Itoa(diff.MaxLines)+" líneas; no se pueden comparar")
+		return
+	}
+	if err != nil {
+		writeInternalError(w, r, err, "Error al comparar las revisiones")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisionDiff{
+		From:  from,
+		To:    to,
+		Lines: lines,
+	})
+}
+
+// loadRevisionSide busca una revisión o el contenido actual de la nota y
+// devuelve su referencia y su cuerpo. Si falla, ya escribió la respuesta.
+func (h *UserHandler) loadRevisionSide(w http.ResponseWriter, r *http.Request, noteID int32, value, param string) (revisionRef, string, bool) {
+	userID := currentUserID(r)
+
+	if value == "current" {
+		note, err := h.store.GetNote(r.Context(), sqlc.GetNoteParams{ID: noteID, UserID: userID})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeNotFound(w, r, "Nota no encontrada")
+				return revisionRef{}, "", false
+			}
+			writeInternalError(w, r, err, "Error interno")
+			return revisionRef{}, "", false
+		}
+		ref := revisionRef{Title: note.Title}
+		if note.UpdatedAt.Valid {
+			ref.CreatedAt = &note.UpdatedAt.Time
+		}
+		return ref, note.Body.String, true
+	}
+
+	revID, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		writeValidationError(w, r, fieldError{param, param + " inválido"})
+		return revisionRef{}, "", false
+	}
+	revision, err := h.store.GetNoteRevision(r.Context(), sqlc.GetNoteRevisionParams{
+		ID:     int32(revID),
+		NoteID: noteID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeNotFound(w, r, "Revisión no encontrada")
+			return revisionRef{}, "", false
+		}
+		writeInternalError(w, r, err, "Error interno")
+		return revisionRef{}, "", false
+	}
+	ref := revisionRef{ID: &revision.ID, Title: revision.Title}
+	if revision.CreatedAt.Valid {
+		ref.CreatedAt = &revision.CreatedAt.Time
+	}
+	return ref, revision.Body.String, true
+}
+
+// restoreNoteRevision vuelve la nota al título, cuerpo y formato de la
+// revisión. El contenido actual queda guardado como una revisión más, así el
+// restore también se puede deshacer. En las checklists no se puede, porque
+// el cuerpo sale de los ítems.
+func (h *UserHandler) restoreNoteRevision(w http.ResponseWriter, r *http.Request) {
+	noteID, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	revID, ok := pathID(w, r, "rev", "ID de revisión inválido")
+	if !ok {
+		return
+	}
+	userID := currentUserID(r)
+
+	var note sqlc.UpdateNoteRow
+	err := h.store.InTx(r.Context(), func(q sqlc.Querier) error {
+		revision, err := q.GetNoteRevision(r.Context(), sqlc.GetNoteRevisionParams{
+			ID:     revID,
+			NoteID: noteID,
+			UserID: userID,
+		})
+		if err != nil {
+			return err
+		}
+		current, err := q.GetNote(r.Context(), sqlc.GetNoteParams{ID: noteID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		if current.Kind == kindChecklist {
+			return errChecklistRestore
+		}
+		if err := h.saveNoteRevision(r.Context(), q, userID, noteID, revision.Title, revision.Body, revision.BodyFormat); err != nil {
+			return err
+		}
+		note, err = q.UpdateNote(r.Context(), sqlc.UpdateNoteParams{
+			ID:         noteID,
+			UserID:     userID,
+			Title:      revision.Title,
+			Body:       revision.Body,
+			BodyFormat: revision.BodyFormat,
+			FolderID:   current.FolderID,
+			Version:    current.Version,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return errStaleVersion
+		}
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeNotFound(w, r, "Revisión no encontrada")
+			return
+		}
+		if errors.Is(err, errStaleVersion) {
+			writeProblem(w, r, http.StatusConflict, codeConflict, "La nota cambió mientras se restauraba, reintentá")
+			return
+		}
+		if errors.Is(err, errChecklistRestore) {
+			writeProblem(w, r, http.StatusConflict, codeConflict, "La nota es una checklist; convertila a texto para restaurar una revisión")
+			return
+		}
+		writeInternalError(w, r, err, "Error al restaurar la revisión")
+		return
+	}
+
+	w.Header().Set("ETag", etag(note.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newNoteDTO(sqlc.GetNoteRow(note)))
+}
+
+// saveNoteRevision guarda el contenido actual de la nota antes de
+// reemplazarlo por title, body y bodyFormat, y borra las revisiones que pasan
+// el límite. Tiene que correr en la misma transacción que la actualización.
+func (h *UserHandler) saveNoteRevision(ctx context.Context, q sqlc.Querier, userID, noteID int32, title string, body sql.NullString, bodyFormat string) error {
+	saved, err := q.SaveNoteRevision(ctx, sqlc.SaveNoteRevisionParams{
+		ID:            noteID,
+		UserID:        userID,
+		NewTitle:      title,
+		NewBody:       body,
+		NewBodyFormat: bodyFormat,
+	})
+	if err != nil {
+		return err
+	}
+	if saved == 0 || h.MaxRevisions <= 0 {
+		return nil
+	}
+	_, err = q.PruneNoteRevisions(ctx, sqlc.PruneNoteRevisionsParams{
+		NoteID: noteID,
+		Keep:   int32(h.MaxRevisions),
+	})
+	return err
+}
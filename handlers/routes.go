@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// apiPrefix es el prefijo de la versión actual de la API. Las mismas rutas
+// siguen respondiendo bajo /api mientras los clientes migran.
+const (
+	apiPrefix       = "/api/v1"
+	legacyAPIPrefix = "/api"
+)
+
+// Routes arma el router de la API. Las rutas incluyen el método, así que el
+// mux responde 405 con el header Allow cuando la ruta existe pero el método no.
+func (h *UserHandler) Routes() http.Handler {
+	mux := http.NewServeMux()
+
+	public := func(pattern string, handler http.HandlerFunc) {
+		method, path, _ := strings.Cut(pattern, " ")
+		mux.HandleFunc(method+" "+apiPrefix+path, handler)
+		mux.HandleFunc(method+" "+legacyAPIPrefix+path, handler)
+	}
+	private := func(pattern string, handler http.HandlerFunc) {
+		public(pattern, h.RequireAuth(handler))
+	}
+
+	public("POST /register", h.RegisterHandler)
+	public("POST /login", h.LoginHandler)
+	public("POST /logout", h.LogoutHandler)
+
+	private("GET /notes", h.getNotes)
+	private("POST /notes", h.createNote)
+	private("GET /notes/search", h.searchNotes)
+	private("GET /notes/{id}", h.getNoteByID)
+	private("PUT /notes/{id}", h.updateNote)
+	private("PATCH /notes/{id}", h.updateNote)
+	private("DELETE /notes/{id}", h.deleteNote)
+	private("POST /notes/{id}/tags", h.attachTag)
+	private("DELETE /notes/{id}/tags/{tag_id}", h.detachTag)
+	private("GET /notes/{id}/revisions", h.listNoteRevisions)
+	private("GET /notes/{id}/revisions/diff", h.diffNoteRevisions)
+	private("GET /notes/{id}/revisions/{rev}", h.getNoteRevision)
+	private("POST /notes/{id}/revisions/{rev}/restore", h.restoreNoteRevision)
+	private("POST /notes/{id}/convert", h.convertNote)
+	private("GET /notes/{id}/items", h.listNoteItems)
+	private("POST /notes/{id}/items", h.createNoteItem)
+	private("PUT /notes/{id}/items/order", h.reorderNoteItems)
+	private("POST /notes/{id}/items/move-checked-down", h.moveCheckedItemsDown)
+	private("PATCH /notes/{id}/items/{item}", h.updateNoteItem)
+	private("DELETE /notes/{id}/items/{item}", h.deleteNoteItem)
+
+	private("GET /folders", h.getFolders)
+	private("POST /folders", h.createFolder)
+	private("GET /folders/tree", h.getFolderTree)
+	private("GET /folders/{id}", h.getFolderByID)
+	private("PUT /folders/{id}", h.updateFolder)
+	private("PATCH /folders/{id}", h.updateFolder)
+	private("DELETE /folders/{id}", h.deleteFolder)
+	private("POST /folders/{id}/move", h.moveFolderHandler)
+
+	private("GET /tags", h.getTags)
+	private("POST /tags", h.createTag)
+	private("PUT /tags/{id}", h.renameTag)
+	private("DELETE /tags/{id}", h.deleteTag)
+	private("POST /tags/{id}/merge", h.mergeTag)
+
+	private("GET /trash", h.getTrash)
+	private("POST /trash/notes/{id}/restore", func(w http.ResponseWriter, r *http.Request) {
+		h.restoreFromTrash(w, r, "notes")
+	})
+	private("DELETE /trash/notes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		h.deleteFromTrash(w, r, "notes")
+	})
+	private("POST /trash/folders/{id}/restore", func(w http.ResponseWriter, r *http.Request) {
+		h.restoreFromTrash(w, r, "folders")
+	})
+	private("DELETE /trash/folders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		h.deleteFromTrash(w, r, "folders")
+	})
+
+	private("GET /users", h.getUsers)
+	private("POST /users", h.createUser)
+	private("GET /users/{id}", h.getUserByID)
+	private("PUT /users/{id}", h.updateUser)
+	private("DELETE /users/{id}", h.deleteUser)
+
+	return problemFallback(mux)
+}
+
+// problemFallback cambia las respuestas en texto plano que da el mux cuando
+// ninguna ruta coincide (404, o 405 con Allow) por problem+json.
+func problemFallback(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		// El handler del mux solo setea Allow y el status; lo corremos sobre
+		// un recorder para saber cuál de los dos casos es
+		rec := &statusRecorder{header: http.Header{}}
+		handler.ServeHTTP(rec, r)
+		if rec.status == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", rec.header.Get("Allow"))
+			writeMethodNotAllowed(w, r)
+			return
+		}
+		writeNotFound(w, r, "No encontrado")
+	})
+}
+
+// statusRecorder es un ResponseWriter que descarta el cuerpo.
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+func (rec *statusRecorder) Header() http.Header         { return rec.header }
+func (rec *statusRecorder) Write(b []byte) (int, error) { return len(b), nil }
+func (rec *statusRecorder) WriteHeader(status int)      { rec.status = status }
+
+// pathID lee un ID numérico de la ruta (por ejemplo {id}). Si no es válido
+// responde 400 con detail y devuelve false.
+func pathID(w http.ResponseWriter, r *http.Request, name, detail string) (int32, bool) {
+	id, err := strconv.ParseInt(r.PathValue(name), 10, 32)
+	if err != nil {
+		writeInvalidID(w, r, detail)
+		return 0, false
+	}
+	return int32(id), true
+}
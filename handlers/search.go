@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	sqlc "tpeweb.com/servidor-go/db/sqlc"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+type searchResult struct {
+	ID             int32      `json:"id"`
+	FolderID       *int32     `json:"folder_id"`
+	Title          string     `json:"title"`
+	TitleHighlight string     `json:"title_highlight"`
+	Snippet        string     `json:"snippet"`
+	Rank           float32    `json:"rank"`
+	UpdatedAt      *time.Time `json:"updated_at"`
+}
+
+// searchNotes atiende GET /api/v1/notes/search?q=...&folder_id=...&limit=...
+//
+// Sintaxis de q: las palabras se combinan con AND, "entre comillas" busca la
+// frase exacta, palabra* busca por prefijo y -palabra excluye resultados.
+func (h *UserHandler) searchNotes(w http.ResponseWriter, r *http.Request) {
+	query := buildTSQuery(r.URL.Query().Get("q"))
+	if query == "" {
+		writeValidationError(w, r, fieldError{"q", "El parámetro q es obligatorio"})
+		return
+	}
+
+	params := sqlc.SearchNotesParams{
+		UserID:     currentUserID(r),
+		Query:      query,
+		MaxResults: defaultSearchLimit,
+	}
+
+	if folderStr := r.URL.Query().Get("folder_id"); folderStr != "" {
+		folderID, err := strconv.ParseInt(folderStr, 10, 32)
+		if err != nil {
+			writeValidationError(w, r, fieldError{"folder_id", "folder_id inválido"})
+			return
+		}
+		if !h.ownsFolder(w, r, int32(folderID)) {
+			return
+		}
+		params.FolderID = sql.NullInt32{Int32: int32(folderID), Valid: true}
+	}
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			writeValidationError(w, r, fieldError{"limit", "limit debe estar entre 1 y " + strconv.Itoa(maxSearchLimit)})
+			return
+		}
+		params.MaxResults = int32(limit)
+	}
+
+	rows, err := h.store.SearchNotes(r.Context(), params)
+	if err != nil {
+		writeInternalError(w, r, err, "Error al buscar notas")
+		return
+	}
+
+	results := make([]searchResult, 0, len(rows))
+	for _, row := range rows {
+		result := searchResult{
+			ID:             row.ID,
+			Title:          row.Title,
+			TitleHighlight: highlight(row.TitleHighlight),
+			Snippet:        highlight(row.Snippet),
+			Rank:           row.Rank,
+		}
+		if row.FolderID.Valid {
+			result.FolderID = &row.FolderID.Int32
+		}
+		if row.UpdatedAt.Valid {
+			result.UpdatedAt = &row.UpdatedAt.Time
+		}
+		results = append(results, result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(results)
+	if err != nil {
+		writeInternalError(w, r, err, "Error al codificar JSON")
+		return
+	}
+}
+
+// highlight escapa el texto de la nota y recién después convierte los
+// marcadores que agrega ts_headline en <mark>, para no devolver HTML del usuario.
+func highlight(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, "[[[", "<mark>")
+	return strings.ReplaceAll(s, "]]]", "</mark>")
+}
+
+// buildTSQuery traduce la búsqueda del usuario a la sintaxis de to_tsquery.
+// Los lexemas solo contienen letras y números, así el resultado siempre es
+// un tsquery válido. Devuelve "" si no queda ningún término.
+func buildTSQuery(q string) string {
+	var terms []string
+
+	for len(q) > 0 {
+		q = strings.TrimLeftFunc(q, unicode.IsSpace)
+		if q == "" {
+			break
+		}
+
+		negate := false
+		if q[0] == '-' {
+			negate = true
+			q = q[1:]
+		}
+
+		var term string
+		if strings.HasPrefix(q, `"`) {
+			// Frase: las palabras tienen que aparecer seguidas
+			end := strings.Index(q[1:], `"`)
+			var phrase string
+			if end < 0 {
+				phrase, q = q[1:], ""
+			} else {
+				phrase, q = q[1:end+1], q[end+2:]
+			}
+			term = phraseTerm(lexemes(phrase), false)
+		} else {
+			end := strings.IndexFunc(q, unicode.IsSpace)
+			var word string
+			if end < 0 {
+				word, q = q, ""
+			} else {
+				word, q = q[:end], q[end:]
+			}
+			term = phraseTerm(lexemes(word), strings.HasSuffix(word, "*"))
+		}
+
+		if term == "" {
+			continue
+		}
+		if negate {
+			term = "!" + term
+		}
+		terms = append(terms, term)
+	}
+
+	// Una búsqueda solo con exclusiones no tiene sentido para to_tsquery
+	for _, term := range terms {
+		if !strings.HasPrefix(term, "!") {
+			return strings.Join(terms, " & ")
+		}
+	}
+	return ""
+}
+
+// lexemes separa el texto igual que el parser de Postgres: cualquier carácter
+// que no sea letra o número corta la palabra ("e-mail" son dos lexemas).
+func lexemes(text string) []string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+	return words
+}
+
+// phraseTerm une los lexemas con <-> (seguidos) y opcionalmente marca el
+// último como prefijo.
+func phraseTerm(words []string, prefix bool) string {
+	if len(words) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = "'" + word + "'"
+	}
+	if prefix {
+		quoted[len(quoted)-1] += ":*"
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return "(" + strings.Join(quoted, " <-> ") + ")"
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	sqlc "tpeweb.com/servidor-go/db/sqlc"
+	"tpeweb.com/servidor-go/store"
+)
+
+const maxTagNameLength = 50
+
+// ============= TAGS HANDLERS =============
+
+func (h *UserHandler) getTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.store.ListTags(r.Context(), currentUserID(r))
+	if err != nil {
+		writeInternalError(w, r, err, "Error al listar etiquetas")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapSlice(tags, newTagWithCountDTO))
+}
+
+func (h *UserHandler) createTag(w http.ResponseWriter, r *http.Request) {
+	name, ok := decodeTagName(w, r)
+	if !ok {
+		return
+	}
+
+	tag, err := h.store.CreateTag(r.Context(), sqlc.CreateTagParams{
+		UserID: currentUserID(r),
+		Name:   name,
+	})
+	if err != nil {
+		if store.IsUniqueViolation(err) {
+			writeProblem(w, r, http.StatusConflict, codeAlreadyExists, "Ya existe una etiqueta con ese nombre")
+			return
+		}
+		writeInternalError(w, r, err, "Error al crear etiqueta")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newTagDTO(tag))
+}
+
+func (h *UserHandler) renameTag(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	name, ok := decodeTagName(w, r)
+	if !ok {
+		return
+	}
+
+	tag, err := h.store.RenameTag(r.Context(), sqlc.RenameTagParams{
+		ID:     id,
+		UserID: currentUserID(r),
+		Name:   name,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			writeNotFound(w, r, "No encontrado")
+		case store.IsUniqueViolation(err):
+			writeProblem(w, r, http.StatusConflict, codeAlreadyExists, "Ya existe una etiqueta con ese nombre; usá merge para unirlas")
+		default:
+			writeInternalError(w, r, err, "Error al renombrar etiqueta")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newTagDTO(tag))
+}
+
+func (h *UserHandler) deleteTag(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	deleted, err := h.store.DeleteTag(r.Context(), sqlc.DeleteTagParams{ID: id, UserID: currentUserID(r)})
+	if err != nil {
+		writeInternalError(w, r, err, "Error al borrar etiqueta")
+		return
+	}
+	if deleted == 0 {
+		writeNotFound(w, r, "No encontrado")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// mergeTag une la etiqueta id en {"into_tag_id": N}: las notas de id pasan a
+// tener la etiqueta destino y id se borra.
+func (h *UserHandler) mergeTag(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	var input struct {
+		IntoTagID int32 `json:"into_tag_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeInvalidJSON(w, r)
+		return
+	}
+	if input.IntoTagID == id {
+		writeValidationError(w, r, fieldError{"into_tag_id", "No se puede unir una etiqueta consigo misma"})
+		return
+	}
+
+	userID := currentUserID(r)
+	var target sqlc.Tag
+	err := h.store.InTx(r.Context(), func(q sqlc.Querier) error {
+		if _, err := q.GetTag(r.Context(), sqlc.GetTagParams{ID: id, UserID: userID}); err != nil {
+			return err
+		}
+		var err error
+		target, err = q.GetTag(r.Context(), sqlc.GetTagParams{ID: input.IntoTagID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		if err := q.MergeNoteTags(r.Context(), sqlc.MergeNoteTagsParams{TargetID: target.ID, SourceID: id}); err != nil {
+			return err
+		}
+		_, err = q.DeleteTag(r.Context(), sqlc.DeleteTagParams{ID: id, UserID: userID})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeNotFound(w, r, "No encontrado")
+			return
+		}
+		writeInternalError(w, r, err, "Error al unir etiquetas")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newTagDTO(target))
+}
+
+// attachTag agrega una etiqueta a la nota. El body puede traer "tag_id" de
+// una etiqueta existente o "name", que la crea si todavía no existe.
+func (h *UserHandler) attachTag(w http.ResponseWriter, r *http.Request) {
+	noteID, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	var input struct {
+		TagID *int32  `json:"tag_id"`
+		Name  *string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeInvalidJSON(w, r)
+		return
+	}
+	if (input.TagID == nil) == (input.Name == nil) {
+		writeValidationError(w, r, fieldError{"tag_id", "Indicá tag_id o name"})
+		return
+	}
+
+	userID := currentUserID(r)
+	if _, err := h.store.GetNote(r.Context(), sqlc.GetNoteParams{ID: noteID, UserID: userID}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeNotFound(w, r, "No encontrado")
+			return
+		}
+		writeInternalError(w, r, err, "Error interno")
+		return
+	}
+
+	var tag sqlc.Tag
+	var err error
+	if input.TagID != nil {
+		tag, err = h.store.GetTag(r.Context(), sqlc.GetTagParams{ID: *input.TagID, UserID: userID})
+	} else {
+		name := strings.TrimSpace(*input.Name)
+		if msg := validateTagName(name); msg != "" {
+			writeValidationError(w, r, fieldError{"name", msg})
+			return
+		}
+		tag, err = h.store.UpsertTag(r.Context(), sqlc.UpsertTagParams{UserID: userID, Name: name})
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeNotFound(w, r, "Etiqueta no encontrada")
+			return
+		}
+		writeInternalError(w, r, err, "Error al obtener etiqueta")
+		return
+	}
+
+	err = h.store.AttachTag(r.Context(), sqlc.AttachTagParams{NoteID: noteID, TagID: tag.ID})
+	if err != nil {
+		writeInternalError(w, r, err, "Error al etiquetar la nota")
+		return
+	}
+
+	h.writeNoteTags(w, r, noteID)
+}
+
+func (h *UserHandler) detachTag(w http.ResponseWriter, r *http.Request) {
+	noteID, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	tagID, ok := pathID(w, r, "tag_id", "ID de etiqueta inválido")
+	if !ok {
+		return
+	}
+	if _, err := h.store.GetNote(r.Context(), sqlc.GetNoteParams{ID: noteID, UserID: currentUserID(r)}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeNotFound(w, r, "No encontrado")
+			return
+		}
+		writeInternalError(w, r, err, "Error interno")
+		return
+	}
+
+	deleted, err := h.store.DetachTag(r.Context(), sqlc.DetachTagParams{NoteID: noteID, TagID: tagID})
+	if err != nil {
+		writeInternalError(w, r, err, "Error al quitar la etiqueta")
+		return
+	}
+	if deleted == 0 {
+		writeNotFound(w, r, "La nota no tiene esa etiqueta")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeNoteTags responde con las etiquetas actuales de la nota.
+func (h *UserHandler) writeNoteTags(w http.ResponseWriter, r *http.Request, noteID int32) {
+	tags, err := h.store.ListNoteTags(r.Context(), noteID)
+	if err != nil {
+		writeInternalError(w, r, err, "Error al listar etiquetas")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapSlice(tags, newTagDTO))
+}
+
+func decodeTagName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var input struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeInvalidJSON(w, r)
+		return "", false
+	}
+	name := strings.TrimSpace(input.Name)
+	if msg := validateTagName(name); msg != "" {
+		writeValidationError(w, r, fieldError{"name", msg})
+		return "", false
+	}
+	return name, true
+}
+
+func validateTagName(name string) string {
+	if name == "" {
+		return "El nombre es obligatorio"
+	}
+	if len([]rune(name)) > maxTagNameLength {
+		return "El nombre no puede superar los " + strconv.Itoa(maxTagNameLength) + " caracteres"
+	}
+	return ""
+}
+
+// parseTagFilter lee ?tag=a&tag=b y tag_match=all|any (por defecto all).
+func parseTagFilter(r *http.Request) ([]string, bool, error) {
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, tag := range r.URL.Query()["tag"] {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	switch r.URL.Query().Get("tag_match") {
+	case "", "all":
+		return tags, true, nil
+	case "any":
+		return tags, false, nil
+	default:
+		return nil, false, fieldError{"tag_match", "tag_match debe ser all o any"}
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	sqlc "tpeweb.com/servidor-go/db/sqlc"
+)
+
+// ============= TRASH HANDLERS =============
+
+type trashResponse struct {
+	Notes   []noteDTO   `json:"notes"`
+	Folders []folderDTO `json:"folders"`
+}
+
+// getTrash atiende GET /api/v1/trash: lista lo que el usuario borró.
+func (h *UserHandler) getTrash(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+	notes, err := h.store.ListTrashedNotes(r.Context(), userID)
+	if err != nil {
+		writeInternalError(w, r, err, "Error al listar la papelera")
+		return
+	}
+	folders, err := h.store.ListTrashedFolders(r.Context(), userID)
+	if err != nil {
+		writeInternalError(w, r, err, "Error al listar la papelera")
+		return
+	}
+
+	response := trashResponse{
+		Notes:   mapSlice(notes, newTrashedNoteDTO),
+		Folders: mapSlice(folders, newFolderDTO),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// restoreFromTrash devuelve la nota o carpeta a su ubicación original. Si esa
+// ubicación también está en la papelera, el elemento vuelve a la raíz.
+func (h *UserHandler) restoreFromTrash(w http.ResponseWriter, r *http.Request, kind string) {
+	id, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	userID := currentUserID(r)
+
+	var restored int64
+	var err error
+	if kind == "notes" {
+		restored, err = h.store.RestoreNote(r.Context(), sqlc.RestoreNoteParams{ID: id, UserID: userID})
+	} else {
+		restored, err = h.store.RestoreFolder(r.Context(), sqlc.RestoreFolderParams{ID: id, UserID: userID})
+	}
+	if err != nil {
+		writeInternalError(w, r, err, "Error al restaurar")
+		return
+	}
+	if restored == 0 {
+		writeNotFound(w, r, "No encontrado en la papelera")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if kind == "notes" {
+		note, err := h.store.GetNote(r.Context(), sqlc.GetNoteParams{ID: id, UserID: userID})
+		if err != nil {
+			writeInternalError(w, r, err, "Error interno")
+			return
+		}
+		json.NewEncoder(w).Encode(newNoteDTO(note))
+		return
+	}
+	folder, err := h.store.GetFolder(r.Context(), sqlc.GetFolderParams{ID: id, UserID: userID})
+	if err != nil {
+		writeInternalError(w, r, err, "Error interno")
+		return
+	}
+	json.NewEncoder(w).Encode(newFolderDTO(folder))
+}
+
+// deleteFromTrash borra definitivamente un elemento que ya está en la papelera.
+func (h *UserHandler) deleteFromTrash(w http.ResponseWriter, r *http.Request, kind string) {
+	id, ok := pathID(w, r, "id", "ID inválido")
+	if !ok {
+		return
+	}
+	userID := currentUserID(r)
+
+	var deleted int64
+	var err error
+	if kind == "notes" {
+		deleted, err = h.store.DeleteNote(r.Context(), sqlc.DeleteNoteParams{ID: id, UserID: userID})
+	} else {
+		deleted, err = h.store.DeleteFolder(r.Context(), sqlc.DeleteFolderParams{ID: id, UserID: userID})
+	}
+	if err != nil {
+		writeInternalError(w, r, err, "Error al borrar")
+		return
+	}
+	if deleted == 0 {
+		writeNotFound(w, r, "No encontrado en la papelera")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PurgeTrash borra definitivamente lo que está en la papelera hace más de
+// retention.
+func (h *UserHandler) PurgeTrash(ctx context.Context, retention time.Duration) error {
+	before := time.Now().Add(-retention)
+	notes, err := h.store.PurgeTrashedNotes(ctx, before)
+	if err != nil {
+		return err
+	}
+	folders, err := h.store.PurgeTrashedFolders(ctx, before)
+	if err != nil {
+		return err
+	}
+	if notes > 0 || folders > 0 {
+		slog.Info("Papelera vaciada", "notes", notes, "folders", folders, "retention", retention)
+	}
+	return nil
+}
+
+// RunTrashPurger vacía la papelera cada interval hasta que se cancele ctx.
+func (h *UserHandler) RunTrashPurger(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := h.PurgeTrash(ctx, retention); err != nil && ctx.Err() == nil {
+			slog.Error("No se pudo vaciar la papelera", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
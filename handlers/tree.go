@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	sqlc "tpeweb.com/servidor-go/db/sqlc"
+)
+
+// folderTreeNode es una carpeta del árbol con sus subcarpetas anidadas.
+type folderTreeNode struct {
+	ID             int32             `json:"id"`
+	Name           string            `json:"name"`
+	Description    *string           `json:"description"`
+	ParentFolderID *int32            `json:"parent_folder_id"`
+	NoteCount      int32             `json:"note_count"`
+	TotalNoteCount int32             `json:"total_note_count"`
+	Children       []*folderTreeNode `json:"children"`
+}
+
+type folderTreeResponse struct {
+	TotalNotes   int64             `json:"total_notes"`
+	UnfiledNotes int64             `json:"unfiled_notes"`
+	Folders      []*folderTreeNode `json:"folders"`
+}
+
+func (h *UserHandler) getFolderTree(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	rows, err := h.store.ListFolderTree(r.Context(), userID)
+	if err != nil {
+		writeInternalError(w, r, err, "Error al listar carpetas")
+		return
+	}
+	counts, err := h.store.CountNotes(r.Context(), userID)
+	if err != nil {
+		writeInternalError(w, r, err, "Error al contar notas")
+		return
+	}
+
+	response := folderTreeResponse{
+		TotalNotes:   counts.Total,
+		UnfiledNotes: counts.Unfiled,
+		Folders:      buildFolderTree(rows),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		writeInternalError(w, r, err, "Error al codificar JSON")
+		return
+	}
+}
+
+// buildFolderTree arma la jerarquía a partir de la lista plana. Las filas
+// vienen ordenadas por nombre, así que los hijos quedan ordenados también.
+func buildFolderTree(rows []sqlc.ListFolderTreeRow) []*folderTreeNode {
+	nodes := make(map[int32]*folderTreeNode, len(rows))
+	for _, row := range rows {
+		node := &folderTreeNode{
+			ID:             row.ID,
+			Name:           row.Name,
+			NoteCount:      row.NoteCount,
+			TotalNoteCount: row.TotalNoteCount,
+			Children:       []*folderTreeNode{},
+		}
+		if row.Description.Valid {
+			node.Description = &row.Description.String
+		}
+		if row.ParentFolderID.Valid {
+			node.ParentFolderID = &row.ParentFolderID.Int32
+		}
+		nodes[row.ID] = node
+	}
+
+	roots := []*folderTreeNode{}
+	for _, row := range rows {
+		node := nodes[row.ID]
+		parent, ok := nodes[row.ParentFolderID.Int32]
+		if row.ParentFolderID.Valid && ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
@@ -0,0 +1,811 @@
+//go:build integration
+
+// Suite de integración: levanta el servidor completo (newHandler) con
+// httptest y lo recorre por HTTP como un cliente real.
+//
+//	go test -tags integration -count=1 .
+//
+// Con TEST_DATABASE_URL (URL postgres://) cada test corre contra Postgres en
+// un schema propio que se crea, se migra y se borra al terminar. Con
+// TEST_DB_DRIVER=sqlite cada test usa un archivo SQLite nuevo en una carpeta
+// temporal. Sin ninguna de las dos usa store.Memory.
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"tpeweb.com/servidor-go/config"
+	handlerDB "tpeweb.com/servidor-go/db/handlers"
+	"tpeweb.com/servidor-go/db/migrations"
+	"tpeweb.com/servidor-go/handlers"
+	"tpeweb.com/servidor-go/store"
+)
+
+// newTestServer arranca el servidor sobre HTTPS, porque la cookie de sesión
+// es Secure y el cliente no la manda por HTTP.
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	cfg := config.Default()
+	cfg.StaticDir = "static"
+
+	var s store.Store = store.NewMemory()
+	if dbURL := os.Getenv("TEST_DATABASE_URL"); dbURL != "" {
+		s = newSchemaStore(t, cfg.Database, dbURL)
+	} else if os.Getenv("TEST_DB_DRIVER") == config.DriverSQLite {
+		s = newSQLiteStore(t, cfg.Database)
+	}
+	userHandler := handlers.NewUserHandler(s)
+	userHandler.MaxRevisions = cfg.NoteRevisionsMax
+	userHandler.CookieSecure = cfg.CookieSecure
+
+	srv := httptest.NewTLSServer(newHandler(cfg, userHandler))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newSchemaStore crea un schema descartable, le aplica las migraciones y
+// devuelve un store.SQL que trabaja solo ahí.
+func newSchemaStore(t *testing.T, dbCfg config.Database, dbURL string) store.Store {
+	t.Helper()
+	admin, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	suffix := make([]byte, 6)
+	rand.Read(suffix)
+	schema := "it_" + hex.EncodeToString(suffix)
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("no se pudo crear el schema de prueba: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("no se pudo borrar el schema %s: %v", schema, err)
+		}
+	})
+
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		t.Fatalf("TEST_DATABASE_URL inválida: %v", err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	dbCfg.URL = u.String()
+
+	conn, err := handlerDB.ConnectDB(dbCfg)
+	if err != nil {
+		t.Fatalf("no se pudo conectar a la base: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if _, err := migrations.Postgres.Up(context.Background(), conn); err != nil {
+		t.Fatalf("no se pudieron aplicar las migraciones: %v", err)
+	}
+	return store.NewSQL(conn)
+}
+
+// newSQLiteStore crea una base SQLite vacía en la carpeta temporal del test
+// y le aplica las migraciones.
+func newSQLiteStore(t *testing.T, dbCfg config.Database) store.Store {
+	t.Helper()
+	dbCfg.Driver = config.DriverSQLite
+	dbCfg.URL = filepath.Join(t.TempDir(), "keepnotes.db")
+
+	conn, err := handlerDB.ConnectDB(dbCfg)
+	if err != nil {
+		t.Fatalf("no se pudo abrir la base: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if _, err := migrations.SQLite.Up(context.Background(), conn); err != nil {
+		t.Fatalf("no se pudieron aplicar las migraciones: %v", err)
+	}
+	return store.NewSQLite(conn)
+}
+
+// apiClient es un usuario de la API con su propio cookie jar.
+type apiClient struct {
+	t    *testing.T
+	base string
+	http *http.Client
+}
+
+func newAPIClient(t *testing.T, srv *httptest.Server) *apiClient {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := *srv.Client()
+	client.Jar = jar
+	return &apiClient{t: t, base: srv.URL, http: &client}
+}
+
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// do manda la request; body se codifica como JSON salvo que sea un string.
+// headers son pares nombre, valor.
+func (c *apiClient) do(method, path string, body any, headers ...string) response {
+	c.t.Helper()
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = bytes.NewReader([]byte(b))
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.base+path, reader)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return response{status: resp.StatusCode, header: resp.Header, body: data}
+}
+
+// expect verifica el status y decodifica el body en v (si no es nil).
+func (c *apiClient) expect(resp response, status int, v any) {
+	c.t.Helper()
+	if resp.status != status {
+		c.t.Fatalf("status = %d, se esperaba %d; body: %s", resp.status, status, resp.body)
+	}
+	if v != nil {
+		if err := json.Unmarshal(resp.body, v); err != nil {
+			c.t.Fatalf("respuesta con JSON inválido: %v; body: %s", err, resp.body)
+		}
+	}
+}
+
+type problem struct {
+	Status int    `json:"status"`
+	Code   string `json:"code"`
+	Errors []struct {
+		Field string `json:"field"`
+	} `json:"errors"`
+}
+
+// expectProblem verifica que la respuesta sea un problem+json con ese status y code.
+func (c *apiClient) expectProblem(resp response, status int, code string) problem {
+	c.t.Helper()
+	var p problem
+	c.expect(resp, status, &p)
+	if ct := resp.header.Get("Content-Type"); ct != "application/problem+json" {
+		c.t.Fatalf("Content-Type = %q, se esperaba application/problem+json", ct)
+	}
+	if p.Code != code || p.Status != status {
+		c.t.Fatalf("problem = %+v, se esperaba code %q", p, code)
+	}
+	return p
+}
+
+// register crea el usuario y devuelve su ID.
+func (c *apiClient) register(username, password string) int32 {
+	c.t.Helper()
+	var user struct {
+		ID int32 `json:"id"`
+	}
+	c.expect(c.do("POST", "/api/v1/register", map[string]string{
+		"username": username,
+		"email":    username + "@example.com",
+		"password": password,
+	}), http.StatusCreated, &user)
+	return user.ID
+}
+
+func (c *apiClient) login(username, password string) {
+	c.t.Helper()
+	c.expect(c.do("POST", "/api/v1/login", map[string]string{"username": username, "password": password}), http.StatusOK, nil)
+}
+
+// signUp registra al usuario y se loguea; devuelve su ID.
+func (c *apiClient) signUp(username string) int32 {
+	c.t.Helper()
+	id := c.register(username, "secreto123")
+	c.login(username, "secreto123")
+	return id
+}
+
+func resource(collection string, id int32) string {
+	return "/api/v1/" + collection + "/" + strconv.Itoa(int(id))
+}
+
+type note struct {
+	ID         int32   `json:"id"`
+	FolderID   *int32  `json:"folder_id"`
+	Title      string  `json:"title"`
+	Body       *string `json:"body"`
+	BodyFormat string  `json:"body_format"`
+	Kind       string  `json:"kind"`
+	Version    int32   `json:"version"`
+}
+
+type folder struct {
+	ID             int32   `json:"id"`
+	Name           string  `json:"name"`
+	Description    *string `json:"description"`
+	ParentFolderID *int32  `json:"parent_folder_id"`
+	Version        int32   `json:"version"`
+}
+
+func (c *apiClient) createNote(fields map[string]any) note {
+	c.t.Helper()
+	var n note
+	c.expect(c.do("POST", "/api/v1/notes", fields), http.StatusCreated, &n)
+	return n
+}
+
+func (c *apiClient) createFolder(fields map[string]any) folder {
+	c.t.Helper()
+	var f folder
+	c.expect(c.do("POST", "/api/v1/folders", fields), http.StatusCreated, &f)
+	return f
+}
+
+func ifMatch(version int32) []string {
+	return []string{"If-Match", fmt.Sprintf(`"%d"`, version)}
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	srv := newTestServer(t)
+	c := newAPIClient(t, srv)
+
+	var status struct {
+		Status string `json:"status"`
+	}
+	c.expect(c.do("GET", "/healthz", nil), http.StatusOK, &status)
+	c.expect(c.do("GET", "/readyz", nil), http.StatusOK, &status)
+	if status.Status != "ok" {
+		t.Fatalf("readyz = %q", status.Status)
+	}
+	c.expect(c.do("GET", "/version", nil), http.StatusOK, nil)
+
+	resp := c.do("GET", "/healthz", nil)
+	if resp.header.Get("X-Request-ID") == "" {
+		t.Fatal("la respuesta no trae X-Request-ID")
+	}
+}
+
+func TestUsersCRUD(t *testing.T) {
+	srv := newTestServer(t)
+	ana := newAPIClient(t, srv)
+	anaID := ana.signUp("ana")
+	beto := newAPIClient(t, srv)
+	betoID := beto.signUp("beto")
+
+	var user struct {
+		ID       int32  `json:"id"`
+		Username string `json:"username"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	ana.expect(ana.do("GET", resource("users", anaID), nil), http.StatusOK, &user)
+	if user.Username != "ana" || user.Email != "ana@example.com" || user.Password != "" {
+		t.Fatalf("usuario = %+v", user)
+	}
+
+	// Los datos de los demás no se ven, ni sueltos ni en el listado
+	ana.expectProblem(ana.do("GET", resource("users", betoID), nil), http.StatusForbidden, "forbidden")
+	var page struct {
+		Items []struct {
+			Username string `json:"username"`
+		} `json:"items"`
+	}
+	ana.expect(ana.do("GET", "/api/v1/users?sort=username&order=desc", nil), http.StatusOK, &page)
+	if len(page.Items) != 1 || page.Items[0].Username != "ana" {
+		t.Fatalf("usuarios = %+v", page.Items)
+	}
+
+	ana.expect(ana.do("PUT", resource("users", anaID), map[string]string{
+		"username": "ana2",
+		"email":    "ana2@example.com",
+		"password": "nueva456",
+	}), http.StatusOK, nil)
+	ana.expect(ana.do("GET", resource("users", anaID), nil), http.StatusOK, &user)
+	if user.Username != "ana2" || user.Email != "ana2@example.com" {
+		t.Fatalf("usuario actualizado = %+v", user)
+	}
+	other := newAPIClient(t, srv)
+	other.expectProblem(other.do("POST", "/api/v1/login", map[string]string{"username": "ana2", "password": "secreto123"}),
+		http.StatusUnauthorized, "invalid_credentials")
+	other.login("ana2", "nueva456")
+
+	// No se puede tomar el nombre de otro usuario
+	ana.expectProblem(ana.do("PUT", resource("users", anaID), map[string]string{
+		"username": "beto",
+		"email":    "ana2@example.com",
+		"password": "nueva456",
+	}), http.StatusConflict, "already_exists")
+
+	beto.expect(beto.do("DELETE", resource("users", betoID), nil), http.StatusNoContent, nil)
+	beto.expectProblem(beto.do("GET", "/api/v1/notes", nil), http.StatusUnauthorized, "unauthenticated")
+}
+
+func TestLoginFailures(t *testing.T) {
+	srv := newTestServer(t)
+	c := newAPIClient(t, srv)
+	c.register("ana", "secreto123")
+
+	cases := []struct {
+		name   string
+		body   any
+		status int
+		code   string
+	}{
+		{"contraseña incorrecta", map[string]string{"username": "ana", "password": "otra"}, http.StatusUnauthorized, "invalid_credentials"},
+		{"usuario inexistente", map[string]string{"username": "nadie", "password": "secreto123"}, http.StatusUnauthorized, "invalid_credentials"},
+		{"sin contraseña", map[string]string{"username": "ana"}, http.StatusBadRequest, "validation_failed"},
+		{"JSON inválido", `{"username":`, http.StatusBadRequest, "invalid_json"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c.t = t
+			c.expectProblem(c.do("POST", "/api/v1/login", tc.body), tc.status, tc.code)
+		})
+	}
+	c.t = t
+
+	c.expectProblem(c.do("GET", "/api/v1/notes", nil), http.StatusUnauthorized, "unauthenticated")
+	c.expectProblem(c.do("GET", "/api/v1/notes", nil, "Cookie", "keepnotes_session=inventada"),
+		http.StatusUnauthorized, "unauthenticated")
+
+	c.login("ana", "secreto123")
+	c.expect(c.do("GET", "/api/v1/notes", nil), http.StatusOK, nil)
+	c.expect(c.do("POST", "/api/v1/logout", nil), http.StatusNoContent, nil)
+	c.expectProblem(c.do("GET", "/api/v1/notes", nil), http.StatusUnauthorized, "unauthenticated")
+
+	c.expectProblem(c.do("POST", "/api/v1/register", map[string]string{
+		"username": "ana",
+		"email":    "otra@example.com",
+		"password": "x",
+	}), http.StatusConflict, "already_exists")
+}
+
+func TestNotesCRUD(t *testing.T) {
+	srv := newTestServer(t)
+	c := newAPIClient(t, srv)
+	c.signUp("ana")
+
+	created := c.createNote(map[string]any{"title": "Compras", "body": "pan"})
+	path := resource("notes", created.ID)
+
+	var got note
+	resp := c.do("GET", path, nil)
+	c.expect(resp, http.StatusOK, &got)
+	if got.Title != "Compras" || got.Body == nil || *got.Body != "pan" || resp.header.Get("ETag") != `"1"` {
+		t.Fatalf("nota = %+v, ETag %s", got, resp.header.Get("ETag"))
+	}
+
+	var page struct {
+		Items []note `json:"items"`
+	}
+	c.expect(c.do("GET", "/api/v1/notes", nil), http.StatusOK, &page)
+	if len(page.Items) != 1 || page.Items[0].ID != created.ID {
+		t.Fatalf("notas = %+v", page.Items)
+	}
+
+	c.expect(c.do("PUT", path, map[string]any{"title": "Compras", "body": "pan y leche"}, ifMatch(1)...), http.StatusOK, &got)
+	if got.Version != 2 || *got.Body != "pan y leche" {
+		t.Fatalf("nota con PUT = %+v", got)
+	}
+	c.expect(c.do("PATCH", path, `{"title": "Súper"}`, append(ifMatch(2), "Content-Type", "application/merge-patch+json")...),
+		http.StatusOK, &got)
+	if got.Version != 3 || got.Title != "Súper" || *got.Body != "pan y leche" {
+		t.Fatalf("nota con PATCH = %+v", got)
+	}
+
+	var revisions []struct {
+		Title string `json:"title"`
+	}
+	c.expect(c.do("GET", path+"/revisions", nil), http.StatusOK, &revisions)
+	if len(revisions) != 2 || revisions[0].Title != "Compras" {
+		t.Fatalf("revisiones = %+v", revisions)
+	}
+
+	c.expect(c.do("DELETE", path, nil, ifMatch(3)...), http.StatusNoContent, nil)
+	c.expectProblem(c.do("GET", path, nil), http.StatusNotFound, "not_found")
+
+	var trash struct {
+		Notes []note `json:"notes"`
+	}
+	c.expect(c.do("GET", "/api/v1/trash", nil), http.StatusOK, &trash)
+	if len(trash.Notes) != 1 || trash.Notes[0].ID != created.ID {
+		t.Fatalf("papelera = %+v", trash)
+	}
+	c.expect(c.do("POST", resource("trash/notes", created.ID)+"/restore", nil), http.StatusOK, nil)
+	c.expect(c.do("GET", path, nil), http.StatusOK, nil)
+
+	c.expect(c.do("DELETE", path, nil, ifMatch(3)...), http.StatusNoContent, nil)
+	c.expect(c.do("DELETE", resource("trash/notes", created.ID), nil), http.StatusNoContent, nil)
+	c.expectProblem(c.do("POST", resource("trash/notes", created.ID)+"/restore", nil), http.StatusNotFound, "not_found")
+}
+
+func TestMarkdownNotes(t *testing.T) {
+	srv := newTestServer(t)
+	c := newAPIClient(t, srv)
+	c.signUp("ana")
+
+	plain := c.createNote(map[string]any{"title": "Vieja", "body": "pan"})
+	if plain.BodyFormat != "plain" {
+		t.Fatalf("nota sin body_format = %+v", plain)
+	}
+
+	created := c.createNote(map[string]any{"title": "Lista", "body": "- [ ] **pan**\n\n<img src=x onerror=alert(1)>", "body_format": "markdown"})
+	path := resource("notes", created.ID)
+	resp := c.do("GET", path+"?format=html", nil)
+	c.expect(resp, http.StatusOK, nil)
+	html := string(resp.body)
+	if !strings.Contains(html, "<strong>pan</strong>") || !strings.Contains(html, `type="checkbox"`) || strings.Contains(html, "<img") {
+		t.Fatalf("HTML = %q", html)
+	}
+
+	var got note
+	c.expect(c.do("PATCH", path, `{"body_format": "plain"}`, append(ifMatch(1), "Content-Type", "application/merge-patch+json")...),
+		http.StatusOK, &got)
+	if got.BodyFormat != "plain" {
+		t.Fatalf("nota con PATCH = %+v", got)
+	}
+
+	// El cambio de formato deja una revisión, y restaurarla vuelve a markdown
+	var revisions []struct {
+		ID int32 `json:"id"`
+	}
+	c.expect(c.do("GET", path+"/revisions", nil), http.StatusOK, &revisions)
+	if len(revisions) != 1 {
+		t.Fatalf("revisiones = %+v", revisions)
+	}
+	c.expect(c.do("POST", path+"/revisions/"+strconv.Itoa(int(revisions[0].ID))+"/restore", nil), http.StatusOK, &got)
+	if got.BodyFormat != "markdown" {
+		t.Fatalf("nota restaurada = %+v", got)
+	}
+}
+
+func TestChecklistNotes(t *testing.T) {
+	srv := newTestServer(t)
+	c := newAPIClient(t, srv)
+	c.signUp("ana")
+
+	created := c.createNote(map[string]any{"title": "Compras", "body": "pan\nleche"})
+	path := resource("notes", created.ID)
+	var got note
+	c.expect(c.do("POST", path+"/convert", map[string]any{"kind": "checklist"}, ifMatch(1)...), http.StatusOK, &got)
+	if got.Kind != "checklist" || *got.Body != "- [ ] pan\n- [ ] leche\n" {
+		t.Fatalf("nota convertida = %+v", got)
+	}
+
+	type item struct {
+		ID       int32  `json:"id"`
+		Text     string `json:"text"`
+		Checked  bool   `json:"checked"`
+		Position int32  `json:"position"`
+	}
+	var added item
+	c.expect(c.do("POST", path+"/items", map[string]any{"text": "café", "position": 1}), http.StatusCreated, &added)
+	var items []item
+	c.expect(c.do("GET", path+"/items", nil), http.StatusOK, &items)
+	if len(items) != 3 || items[1].ID != added.ID || items[2].Text != "leche" {
+		t.Fatalf("ítems = %+v", items)
+	}
+
+	c.expect(c.do("PATCH", path+"/items/"+strconv.Itoa(int(items[0].ID)), `{"checked": true}`, "Content-Type", "application/merge-patch+json"),
+		http.StatusOK, nil)
+	c.expect(c.do("POST", path+"/items/move-checked-down", nil), http.StatusOK, &items)
+	if items[0].Text != "café" || items[2].Text != "pan" || !items[2].Checked {
+		t.Fatalf("ítems con los tildados abajo = %+v", items)
+	}
+	c.expect(c.do("PUT", path+"/items/order", map[string]any{"item_ids": []int32{items[2].ID, items[1].ID, items[0].ID}}), http.StatusOK, &items)
+	c.expect(c.do("DELETE", path+"/items/"+strconv.Itoa(int(items[1].ID)), nil), http.StatusNoContent, nil)
+
+	// El cuerpo acompaña a los ítems, así la búsqueda los encuentra
+	c.expect(c.do("GET", path, nil), http.StatusOK, &got)
+	if *got.Body != "- [x] pan\n- [ ] café\n" {
+		t.Fatalf("cuerpo = %q", *got.Body)
+	}
+	var results []struct {
+		ID int32 `json:"id"`
+	}
+	c.expect(c.do("GET", "/api/v1/notes/search?q="+url.QueryEscape("café"), nil), http.StatusOK, &results)
+	if len(results) != 1 || results[0].ID != created.ID {
+		t.Fatalf("búsqueda = %+v", results)
+	}
+
+	c.expect(c.do("POST", path+"/convert", map[string]any{"kind": "text"}, ifMatch(got.Version)...), http.StatusOK, &got)
+	c.expect(c.do("GET", path+"/items", nil), http.StatusOK, &items)
+	if got.Kind != "text" || len(items) != 0 {
+		t.Fatalf("nota = %+v, ítems = %+v", got, items)
+	}
+
+	// Los ítems se borran con la nota
+	c.expect(c.do("DELETE", path, nil, ifMatch(got.Version)...), http.StatusNoContent, nil)
+	c.expect(c.do("DELETE", resource("trash/notes", created.ID), nil), http.StatusNoContent, nil)
+}
+
+func TestFoldersCRUD(t *testing.T) {
+	srv := newTestServer(t)
+	c := newAPIClient(t, srv)
+	c.signUp("ana")
+
+	created := c.createFolder(map[string]any{"name": "Trabajo", "description": "Cosas del trabajo"})
+	path := resource("folders", created.ID)
+
+	var got folder
+	c.expect(c.do("GET", path, nil), http.StatusOK, &got)
+	if got.Name != "Trabajo" || got.Description == nil || *got.Description != "Cosas del trabajo" {
+		t.Fatalf("carpeta = %+v", got)
+	}
+
+	var page struct {
+		Items []folder `json:"items"`
+	}
+	c.expect(c.do("GET", "/api/v1/folders", nil), http.StatusOK, &page)
+	if len(page.Items) != 1 {
+		t.Fatalf("carpetas = %+v", page.Items)
+	}
+
+	c.expect(c.do("PUT", path, map[string]any{"name": "Oficina"}, ifMatch(1)...), http.StatusOK, &got)
+	if got.Name != "Oficina" || got.Description != nil || got.Version != 2 {
+		t.Fatalf("carpeta con PUT = %+v", got)
+	}
+	c.expect(c.do("PATCH", path, `{"description": "Reuniones"}`, append(ifMatch(2), "Content-Type", "application/merge-patch+json")...),
+		http.StatusOK, &got)
+	if got.Name != "Oficina" || got.Description == nil || *got.Description != "Reuniones" {
+		t.Fatalf("carpeta con PATCH = %+v", got)
+	}
+
+	c.expect(c.do("DELETE", path, nil, ifMatch(got.Version)...), http.StatusNoContent, nil)
+	c.expectProblem(c.do("GET", path, nil), http.StatusNotFound, "not_found")
+	c.expect(c.do("DELETE", resource("trash/folders", created.ID), nil), http.StatusNoContent, nil)
+	c.expectProblem(c.do("POST", resource("trash/folders", created.ID)+"/restore", nil), http.StatusNotFound, "not_found")
+}
+
+func TestDeleteFolderCascade(t *testing.T) {
+	srv := newTestServer(t)
+	c := newAPIClient(t, srv)
+	c.signUp("ana")
+
+	parent := c.createFolder(map[string]any{"name": "Padre"})
+	sub1 := c.createFolder(map[string]any{"name": "Sub 1", "parent_folder_id": parent.ID})
+	sub2 := c.createFolder(map[string]any{"name": "Sub 2", "parent_folder_id": parent.ID})
+	nested := c.createFolder(map[string]any{"name": "Nieta", "parent_folder_id": sub1.ID})
+	inParent := c.createNote(map[string]any{"title": "En padre", "folder_id": parent.ID})
+	inSub2 := c.createNote(map[string]any{"title": "En sub 2", "folder_id": sub2.ID})
+	inNested := c.createNote(map[string]any{"title": "En nieta", "folder_id": nested.ID})
+	outside := c.createNote(map[string]any{"title": "Suelta"})
+
+	// Una nota que ya estaba en la papelera no vuelve al restaurar la carpeta
+	trashedBefore := c.createNote(map[string]any{"title": "Borrada antes", "folder_id": sub2.ID})
+	c.expect(c.do("DELETE", resource("notes", trashedBefore.ID), nil, ifMatch(1)...), http.StatusNoContent, nil)
+
+	insideFolders := []int32{parent.ID, sub1.ID, sub2.ID, nested.ID}
+	insideNotes := []int32{inParent.ID, inSub2.ID, inNested.ID}
+
+	c.expect(c.do("DELETE", resource("folders", parent.ID), nil, ifMatch(1)...), http.StatusNoContent, nil)
+	for _, id := range insideFolders {
+		c.expectProblem(c.do("GET", resource("folders", id), nil), http.StatusNotFound, "not_found")
+	}
+	for _, id := range insideNotes {
+		c.expectProblem(c.do("GET", resource("notes", id), nil), http.StatusNotFound, "not_found")
+	}
+	c.expect(c.do("GET", resource("notes", outside.ID), nil), http.StatusOK, nil)
+
+	var trash struct {
+		Notes   []note   `json:"notes"`
+		Folders []folder `json:"folders"`
+	}
+	c.expect(c.do("GET", "/api/v1/trash", nil), http.StatusOK, &trash)
+	if len(trash.Folders) != 1 || trash.Folders[0].ID != parent.ID {
+		t.Fatalf("carpetas en la papelera = %+v", trash.Folders)
+	}
+	if len(trash.Notes) != 1 || trash.Notes[0].ID != trashedBefore.ID {
+		t.Fatalf("notas en la papelera = %+v", trash.Notes)
+	}
+
+	c.expect(c.do("POST", resource("trash/folders", parent.ID)+"/restore", nil), http.StatusOK, nil)
+	for _, id := range insideFolders {
+		c.expect(c.do("GET", resource("folders", id), nil), http.StatusOK, nil)
+	}
+	for _, id := range insideNotes {
+		c.expect(c.do("GET", resource("notes", id), nil), http.StatusOK, nil)
+	}
+	c.expectProblem(c.do("GET", resource("notes", trashedBefore.ID), nil), http.StatusNotFound, "not_found")
+
+	// El borrado definitivo se lleva todo el subárbol, incluida la nota que
+	// ya estaba en la papelera
+	c.expect(c.do("DELETE", resource("folders", parent.ID), nil, ifMatch(1)...), http.StatusNoContent, nil)
+	c.expect(c.do("DELETE", resource("trash/folders", parent.ID), nil), http.StatusNoContent, nil)
+	for _, id := range insideFolders {
+		c.expectProblem(c.do("POST", resource("trash/folders", id)+"/restore", nil), http.StatusNotFound, "not_found")
+	}
+	for _, id := range append(insideNotes, trashedBefore.ID) {
+		c.expectProblem(c.do("POST", resource("trash/notes", id)+"/restore", nil), http.StatusNotFound, "not_found")
+	}
+	c.expect(c.do("GET", "/api/v1/trash", nil), http.StatusOK, &trash)
+	if len(trash.Notes) != 0 || len(trash.Folders) != 0 {
+		t.Fatalf("la papelera quedó con %+v", trash)
+	}
+	c.expect(c.do("GET", resource("notes", outside.ID), nil), http.StatusOK, nil)
+}
+
+// TestConcurrentFolderMoves mueve a la vez A dentro de una subcarpeta de B y
+// B dentro de una subcarpeta de A. Cada movimiento por separado es válido,
+// pero los dos juntos cierran un ciclo: uno tiene que ganar y el otro
+// responder folder_cycle.
+func TestConcurrentFolderMoves(t *testing.T) {
+	srv := newTestServer(t)
+	c := newAPIClient(t, srv)
+	c.signUp("ana")
+
+	a := c.createFolder(map[string]any{"name": "A"})
+	a1 := c.createFolder(map[string]any{"name": "A1", "parent_folder_id": a.ID})
+	b := c.createFolder(map[string]any{"name": "B"})
+	b1 := c.createFolder(map[string]any{"name": "B1", "parent_folder_id": b.ID})
+	moves := [][2]int32{{a.ID, b1.ID}, {b.ID, a1.ID}}
+
+	for range 20 {
+		statuses := make([]int, len(moves))
+		var wg sync.WaitGroup
+		for i, move := range moves {
+			wg.Go(func() {
+				statuses[i] = c.do("POST", resource("folders", move[0])+"/move", map[string]any{"parent_folder_id": move[1]}).status
+			})
+		}
+		wg.Wait()
+
+		ok, cycle := 0, 0
+		for _, status := range statuses {
+			switch status {
+			case http.StatusOK:
+				ok++
+			case http.StatusConflict:
+				cycle++
+			}
+		}
+		if ok != 1 || cycle != 1 {
+			t.Fatalf("statuses = %v, se esperaba un 200 y un 409", statuses)
+		}
+
+		for _, id := range []int32{a.ID, b.ID} {
+			c.expect(c.do("POST", resource("folders", id)+"/move", map[string]any{"parent_folder_id": nil}), http.StatusOK, nil)
+		}
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	srv := newTestServer(t)
+	c := newAPIClient(t, srv)
+	c.signUp("ana")
+	otherID := newAPIClient(t, srv).signUp("beto")
+
+	n := c.createNote(map[string]any{"title": "Nota"})
+	parent := c.createFolder(map[string]any{"name": "A"})
+	child := c.createFolder(map[string]any{"name": "B", "parent_folder_id": parent.ID})
+	c.expect(c.do("POST", "/api/v1/tags", map[string]any{"name": "casa"}), http.StatusCreated, nil)
+
+	cases := []struct {
+		name    string
+		method  string
+		path    string
+		body    any
+		headers []string
+		status  int
+		code    string
+	}{
+		{"ruta inexistente", "GET", "/api/v1/nada", nil, nil, http.StatusNotFound, "not_found"},
+		{"método no permitido", "PATCH", "/api/v1/tags/1", nil, nil, http.StatusMethodNotAllowed, "method_not_allowed"},
+		{"ID inválido", "GET", "/api/v1/notes/abc", nil, nil, http.StatusBadRequest, "invalid_id"},
+		{"nota inexistente", "GET", "/api/v1/notes/999999", nil, nil, http.StatusNotFound, "not_found"},
+		{"JSON inválido", "POST", "/api/v1/notes", "{", nil, http.StatusBadRequest, "invalid_json"},
+		{"nota sin título", "POST", "/api/v1/notes", map[string]any{"body": "x"}, nil, http.StatusBadRequest, "validation_failed"},
+		{"carpeta sin nombre", "POST", "/api/v1/folders", map[string]any{}, nil, http.StatusBadRequest, "validation_failed"},
+		{"sort inválido", "GET", "/api/v1/notes?sort=color", nil, nil, http.StatusBadRequest, "validation_failed"},
+		{"cursor inválido", "GET", "/api/v1/notes?cursor=xyz", nil, nil, http.StatusBadRequest, "validation_failed"},
+		{"sin If-Match", "PUT", resource("notes", n.ID), map[string]any{"title": "x"}, nil, http.StatusPreconditionRequired, "if_match_required"},
+		{"If-Match mal formado", "PUT", resource("notes", n.ID), map[string]any{"title": "x"}, []string{"If-Match", "1"}, http.StatusPreconditionFailed, "version_mismatch"},
+		{"PATCH sin merge-patch", "PATCH", resource("notes", n.ID), `{"title":"x"}`, []string{"If-Match", `"1"`, "Content-Type", "text/plain"}, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+		{"carpeta dentro de sí misma", "POST", resource("folders", parent.ID) + "/move", map[string]any{"parent_folder_id": child.ID}, nil, http.StatusConflict, "folder_cycle"},
+		{"etiqueta repetida", "POST", "/api/v1/tags", map[string]any{"name": "casa"}, nil, http.StatusConflict, "already_exists"},
+		{"otro usuario", "DELETE", resource("users", otherID), nil, nil, http.StatusForbidden, "forbidden"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c.t = t
+			c.expectProblem(c.do(tc.method, tc.path, tc.body, tc.headers...), tc.status, tc.code)
+		})
+	}
+	c.t = t
+
+	// Con la versión vieja, 412 trae la copia actual y su ETag
+	c.expect(c.do("PUT", resource("notes", n.ID), map[string]any{"title": "v2"}, ifMatch(1)...), http.StatusOK, nil)
+	resp := c.do("PUT", resource("notes", n.ID), map[string]any{"title": "v3"}, ifMatch(1)...)
+	var current note
+	c.expect(resp, http.StatusPreconditionFailed, &current)
+	if current.Title != "v2" || resp.header.Get("ETag") != `"2"` {
+		t.Fatalf("412 con %+v y ETag %s", current, resp.header.Get("ETag"))
+	}
+}
+
+func TestSearchAndTags(t *testing.T) {
+	srv := newTestServer(t)
+	c := newAPIClient(t, srv)
+	c.signUp("ana")
+
+	recipes := c.createFolder(map[string]any{"name": "Recetas"})
+	breads := c.createFolder(map[string]any{"name": "Panes", "parent_folder_id": recipes.ID})
+	pizza := c.createNote(map[string]any{"title": "Receta de pizza", "body": "harina, agua y levadura", "folder_id": recipes.ID})
+	bread := c.createNote(map[string]any{"title": "Receta de pan", "body": "harina integral y levadura", "folder_id": breads.ID})
+	c.createNote(map[string]any{"title": "Lista", "body": "nada que ver"})
+
+	var results []struct {
+		ID             int32  `json:"id"`
+		TitleHighlight string `json:"title_highlight"`
+	}
+	c.expect(c.do("GET", "/api/v1/notes/search?q="+url.QueryEscape("receta -pan levad*"), nil), http.StatusOK, &results)
+	if len(results) != 1 || results[0].ID != pizza.ID || results[0].TitleHighlight != "<mark>Receta</mark> de pizza" {
+		t.Fatalf("resultados = %+v", results)
+	}
+	c.expect(c.do("GET", fmt.Sprintf("/api/v1/notes/search?q=harina&folder_id=%d", breads.ID), nil), http.StatusOK, &results)
+	if len(results) != 1 || results[0].ID != bread.ID {
+		t.Fatalf("resultados en Panes = %+v", results)
+	}
+
+	// Lo que está en la papelera no aparece
+	c.expect(c.do("DELETE", resource("notes", bread.ID), nil, ifMatch(1)...), http.StatusNoContent, nil)
+	c.expect(c.do("GET", "/api/v1/notes/search?q=harina", nil), http.StatusOK, &results)
+	if len(results) != 1 || results[0].ID != pizza.ID {
+		t.Fatalf("resultados sin la nota borrada = %+v", results)
+	}
+
+	for _, name := range []string{"casa", "urgente"} {
+		c.expect(c.do("POST", resource("notes", pizza.ID)+"/tags", map[string]any{"name": name}), http.StatusOK, nil)
+	}
+	var page struct {
+		Items []note `json:"items"`
+	}
+	c.expect(c.do("GET", "/api/v1/notes?tag=casa&tag=urgente", nil), http.StatusOK, &page)
+	if len(page.Items) != 1 || page.Items[0].ID != pizza.ID {
+		t.Fatalf("notas con casa y urgente = %+v", page.Items)
+	}
+	c.expect(c.do("GET", "/api/v1/notes?tag=otra", nil), http.StatusOK, &page)
+	if len(page.Items) != 0 {
+		t.Fatalf("notas con otra = %+v", page.Items)
+	}
+}
@@ -1,39 +1,171 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
+	"tpeweb.com/servidor-go/config"
 	handlerDB "tpeweb.com/servidor-go/db/handlers"
-	sqlc "tpeweb.com/servidor-go/db/sqlc"
+	"tpeweb.com/servidor-go/db/migrations"
 	"tpeweb.com/servidor-go/handlers"
+	"tpeweb.com/servidor-go/store"
 )
 
 func main() {
-	staticDir := "./static"
-	fileServer := http.FileServer(http.Dir(staticDir))
-	port := ":8080"
-	conn, err := handlerDB.ConnectDB()
+	configPath := flag.String("config", "", "archivo de configuración YAML (por defecto CONFIG_FILE)")
+	printConfig := flag.Bool("print-config", false, "muestra la configuración efectiva sin secretos y sale")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Uso: %s [opciones] [migrate up | down [n] | status]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if *printConfig {
+		out, yamlErr := cfg.Redacted().YAML()
+		if yamlErr != nil {
+			log.Fatal(yamlErr)
+		}
+		os.Stdout.Write(out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Configuración inválida:\n"+err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+	if err != nil {
+		log.Fatal("Configuración inválida:\n" + err.Error())
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: cfg.LogLevel})))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if args := flag.Args(); len(args) > 0 {
+		if args[0] != "migrate" {
+			flag.Usage()
+			os.Exit(2)
+		}
+		err = withDB(cfg, func(conn *sql.DB) error {
+			dialect, err := migrations.ForDriver(cfg.Database.Driver)
+			if err != nil {
+				return err
+			}
+			return runMigrate(ctx, conn, dialect, args[1:])
+		})
+	} else {
+		err = withDB(cfg, func(conn *sql.DB) error {
+			return serve(ctx, cfg, conn)
+		})
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer conn.Close()
-	queries := sqlc.New(conn)
-	userHandler := handlers.NewUserHandler(queries)
-
-	http.Handle("/", fileServer)
-	http.HandleFunc("/api/notes", userHandler.NotesHandler)
-	http.HandleFunc("/api/notes/", userHandler.NoteHandler)
-	http.HandleFunc("/api/folders", userHandler.FoldersHandler)
-	http.HandleFunc("/api/folders/", userHandler.FolderHandler)
-	http.HandleFunc("/api/users", userHandler.UsersHandler)
-	http.HandleFunc("/api/users/", userHandler.SingleUserHandler)
-	http.HandleFunc("/api/login", userHandler.LoginHandler)
-
-	fmt.Printf("Servidor ESTÁTICO escuchando en http://localhost%s\n", port)
-	err = http.ListenAndServe(port, nil)
+}
+
+// withDB abre el pool, corre fn y lo cierra siempre, también cuando fn falla.
+func withDB(cfg config.Config, fn func(conn *sql.DB) error) error {
+	conn, err := handlerDB.ConnectDB(cfg.Database)
 	if err != nil {
-		fmt.Printf("Error al iniciar el servidor: %s\n", err)
+		return err
+	}
+	defer conn.Close()
+	return fn(conn)
+}
+
+// serve atiende requests hasta que se cancele ctx (SIGINT o SIGTERM). Ahí deja
+// de aceptar conexiones, espera hasta ShutdownTimeout a que terminen las
+// requests en curso y frena el vaciado de la papelera antes de volver.
+func serve(ctx context.Context, cfg config.Config, conn *sql.DB) error {
+	if cfg.Database.MigrateOnStart {
+		dialect, err := migrations.ForDriver(cfg.Database.Driver)
+		if err != nil {
+			return err
+		}
+		applied, err := dialect.Up(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range applied {
+			slog.Info("Migración aplicada", "version", m.Version, "name", m.Name)
+		}
 	}
+
+	userHandler := handlers.NewUserHandler(newStore(cfg.Database.Driver, conn))
+	userHandler.MaxRevisions = cfg.NoteRevisionsMax
+	userHandler.CookieSecure = cfg.CookieSecure
+
+	// Los workers se frenan también si el servidor no llega a arrancar
+	workerCtx, stopWorkers := context.WithCancel(ctx)
+	var workers sync.WaitGroup
+	workers.Go(func() {
+		userHandler.RunTrashPurger(workerCtx, time.Hour, cfg.TrashRetention)
+	})
+	defer func() {
+		stopWorkers()
+		workers.Wait()
+	}()
+
+	server := &http.Server{
+		Addr:              cfg.ListenAddr,
+		Handler:           newHandler(cfg, userHandler),
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+		MaxHeaderBytes:    cfg.HTTP.MaxHeaderBytes,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Servidor escuchando", "addr", cfg.ListenAddr)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("error al iniciar el servidor: %w", err)
+	case <-ctx.Done():
+	}
+
+	slog.Info("Apagando el servidor", "timeout", cfg.HTTP.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		// Se venció el plazo: se cortan las conexiones que quedan
+		server.Close()
+		return fmt.Errorf("las requests en curso no terminaron a tiempo: %w", err)
+	}
+	slog.Info("Servidor apagado")
+	return nil
+}
+
+// newStore devuelve el Store del driver configurado.
+func newStore(driver string, conn *sql.DB) store.Store {
+	if driver == config.DriverSQLite {
+		return store.NewSQLite(conn)
+	}
+	return store.NewSQL(conn)
+}
+
+// newHandler arma el handler del servidor: la API, los chequeos de estado y
+// los archivos estáticos.
+func newHandler(cfg config.Config, userHandler *handlers.UserHandler) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.Dir(cfg.StaticDir)))
+	mux.Handle("/api/", userHandler.Routes())
+	mux.HandleFunc("GET /healthz", handlers.Healthz)
+	mux.HandleFunc("GET /readyz", userHandler.Readyz)
+	mux.HandleFunc("GET /version", handlers.Version)
+	return handlers.RequestID(mux)
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"tpeweb.com/servidor-go/db/migrations"
+)
+
+// runMigrate atiende el subcomando "migrate up|down [n]|status".
+func runMigrate(ctx context.Context, conn *sql.DB, dialect *migrations.Dialect, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("uso: migrate up | down [n] | status")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := dialect.Up(ctx, conn)
+		for _, m := range applied {
+			fmt.Printf("aplicada %04d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("no hay migraciones pendientes")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("cantidad de migraciones inválida: %q", args[1])
+			}
+			steps = n
+		}
+		reverted, err := dialect.Down(ctx, conn, steps)
+		for _, m := range reverted {
+			fmt.Printf("revertida %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		statuses, err := dialect.List(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pendiente"
+			if s.AppliedAt != nil {
+				state = "aplicada el " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf("subcomando de migrate desconocido: %s", args[0])
+	}
+}
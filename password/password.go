@@ -0,0 +1,155 @@
+// Package password hashea y verifica contraseñas de usuarios.
+//
+// Los hashes nuevos se generan con argon2id y se guardan en formato PHC
+// ($argon2id$v=19$m=...,t=...,p=...$salt$hash), así el algoritmo y los
+// parámetros viajan junto al hash. Verify también acepta hashes bcrypt y
+// contraseñas legadas en texto plano para poder migrarlas en el próximo login.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidHash indica que un hash argon2id guardado no se puede interpretar.
+var ErrInvalidHash = errors.New("password: hash con formato inválido")
+
+// Params son los parámetros de costo de argon2id.
+type Params struct {
+	Memory      uint32 // en KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultParams sigue la configuración mínima recomendada por OWASP para argon2id.
+var DefaultParams = Params{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+const argon2idPrefix = "$argon2id$"
+
+// Límites para los parámetros de un hash guardado. argon2.IDKey entra en
+// pánico con t o p en 0, y un m enorme reservaría esa memoria en cada login.
+const (
+	maxMemory     = 256 * 1024 // KiB
+	maxIterations = 64
+)
+
+// Hash devuelve la contraseña hasheada con argon2id y DefaultParams.
+func Hash(plain string) (string, error) {
+	return hashWith(plain, DefaultParams)
+}
+
+func hashWith(plain string, p Params) (string, error) {
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(plain), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify compara la contraseña con el hash guardado en tiempo constante.
+// needsRehash es true cuando la contraseña es correcta pero el hash usa un
+// algoritmo o parámetros más débiles que los actuales y conviene regenerarlo.
+func Verify(plain, encoded string) (ok bool, needsRehash bool, err error) {
+	switch {
+	case strings.HasPrefix(encoded, argon2idPrefix):
+		p, salt, key, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false, false, err
+		}
+		other := argon2.IDKey([]byte(plain), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return false, false, nil
+		}
+		return true, weakerThanDefault(p), nil
+
+	case isBcrypt(encoded):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(plain))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, err
+		}
+		return true, true, nil
+
+	default:
+		// Contraseña legada guardada en texto plano
+		if subtle.ConstantTimeCompare([]byte(plain), []byte(encoded)) != 1 {
+			return false, false, nil
+		}
+		return true, true, nil
+	}
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") ||
+		strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$")
+}
+
+func weakerThanDefault(p Params) bool {
+	return p.Memory < DefaultParams.Memory ||
+		p.Iterations < DefaultParams.Iterations ||
+		p.KeyLength < DefaultParams.KeyLength ||
+		p.SaltLength < DefaultParams.SaltLength
+}
+
+func decodeArgon2id(encoded string) (Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+	if version != argon2.Version {
+		return Params{}, nil, nil, fmt.Errorf("password: versión de argon2 no soportada: %d", version)
+	}
+
+	var p Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+	// argon2 pide al menos 8 KiB de memoria por hilo
+	if p.Iterations < 1 || p.Iterations > maxIterations || p.Parallelism < 1 ||
+		p.Memory < 8*uint32(p.Parallelism) || p.Memory > maxMemory {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Params{}, nil, nil, ErrInvalidHash
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+
+	return p, salt, key, nil
+}
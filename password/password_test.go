@@ -0,0 +1,103 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashVerify(t *testing.T) {
+	encoded, err := Hash("secreto123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Fatalf("Hash = %q", encoded)
+	}
+	if other, _ := Hash("secreto123"); other == encoded {
+		t.Fatal("dos hashes de la misma contraseña salieron iguales")
+	}
+
+	ok, needsRehash, err := Verify("secreto123", encoded)
+	if err != nil || !ok || needsRehash {
+		t.Fatalf("Verify = %v, %v, %v", ok, needsRehash, err)
+	}
+	ok, _, err = Verify("otra", encoded)
+	if err != nil || ok {
+		t.Fatalf("Verify con otra contraseña = %v, %v", ok, err)
+	}
+}
+
+func TestVerifyLegacy(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("secreto123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weak, err := hashWith("secreto123", Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"bcrypt", string(bcryptHash)},
+		{"texto plano", "secreto123"},
+		{"argon2id más débil", weak},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash, err := Verify("secreto123", tt.encoded)
+			if err != nil || !ok || !needsRehash {
+				t.Fatalf("Verify = %v, %v, %v; se esperaba que pida rehashear", ok, needsRehash, err)
+			}
+			ok, needsRehash, err = Verify("otra", tt.encoded)
+			if err != nil || ok || needsRehash {
+				t.Fatalf("Verify con otra contraseña = %v, %v, %v", ok, needsRehash, err)
+			}
+		})
+	}
+}
+
+func TestVerifyMalformed(t *testing.T) {
+	encoded, err := Hash("secreto123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(encoded, "$")
+	withParams := func(params string) string {
+		return strings.Join([]string{"", "argon2id", parts[2], params, parts[4], parts[5]}, "$")
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"faltan partes", "$argon2id$v=19$m=19456,t=2,p=1$" + parts[4]},
+		{"sin versión", strings.Replace(encoded, "v=19", "v=x", 1)},
+		{"parámetros ilegibles", withParams("m=19456")},
+		{"t en 0", withParams("m=19456,t=0,p=1")},
+		{"t enorme", withParams("m=19456,t=100000,p=1")},
+		{"p en 0", withParams("m=19456,t=2,p=0")},
+		{"p fuera de rango", withParams("m=19456,t=2,p=300")},
+		{"m muy chico", withParams("m=4,t=2,p=1")},
+		{"m enorme", withParams("m=4294967295,t=2,p=1")},
+		{"salt inválida", strings.Join([]string{"", "argon2id", parts[2], parts[3], "!!", parts[5]}, "$")},
+		{"hash vacío", strings.Join([]string{"", "argon2id", parts[2], parts[3], parts[4], ""}, "$")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, _, err := Verify("secreto123", tt.encoded)
+			if !errors.Is(err, ErrInvalidHash) || ok {
+				t.Fatalf("Verify(%q) = %v, %v; se esperaba ErrInvalidHash", tt.encoded, ok, err)
+			}
+		})
+	}
+
+	if _, _, err := Verify("secreto123", strings.Replace(encoded, "v=19", "v=16", 1)); err == nil || errors.Is(err, ErrInvalidHash) {
+		t.Fatalf("Verify con otra versión de argon2 = %v", err)
+	}
+}
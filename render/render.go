@@ -0,0 +1,85 @@
+// Package render pasa el cuerpo de las notas a HTML para mostrarlo.
+package render
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// Formatos del cuerpo de una nota, como se guardan en note.body_format.
+const (
+	Plain    = "plain"
+	Markdown = "markdown"
+)
+
+// Valid indica si format es uno de los formatos que se pueden guardar.
+func Valid(format string) bool {
+	return format == Plain || format == Markdown
+}
+
+// markdown es CommonMark con las extensiones de GFM: tablas, listas de
+// tareas, tachado y links automáticos. Sin html.WithUnsafe, el HTML que venga
+// en el texto se descarta en lugar de copiarse. La alineación de las columnas
+// va en el atributo align, porque la política no deja pasar style.
+var markdown = goldmark.New(goldmark.WithExtensions(
+	extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+	extension.Strikethrough,
+	extension.Linkify,
+	extension.TaskList,
+))
+
+// policy es la lista de lo que puede salir en el HTML; todo lo demás se saca.
+// No hay imágenes ni atributos de estilo, y los links solo pueden ser http,
+// https o mailto.
+var policy = func() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "hr", "h1", "h2", "h3", "h4", "h5", "h6",
+		"em", "strong", "del", "code", "pre", "blockquote", "ul", "ol", "li",
+		"table", "thead", "tbody", "tr", "th", "td")
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	p.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	// Las casillas de las listas de tareas, que goldmark genera deshabilitadas
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^(|checked|disabled)$`)).OnElements("input")
+	return p
+}()
+
+// HTML devuelve body como un fragmento de HTML seguro para insertar en una
+// página. El texto plano se escapa y cada línea se respeta con <br>.
+func HTML(format, body string) (string, error) {
+	if format != Markdown {
+		return plainHTML(body), nil
+	}
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(body), &buf); err != nil {
+		return "", err
+	}
+	return policy.Sanitize(buf.String()), nil
+}
+
+// plainHTML arma un párrafo por cada bloque separado por líneas en blanco.
+func plainHTML(body string) string {
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	var b strings.Builder
+	for _, paragraph := range strings.Split(body, "\n\n") {
+		paragraph = strings.Trim(paragraph, "\n")
+		if paragraph == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>\n"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
@@ -18,6 +18,17 @@ document.addEventListener('DOMContentLoaded', () => {
         });
     }
 
+    //login / register form event listeners
+    document.querySelector('.auth-form').addEventListener('submit', submitAuth);
+    document.querySelector('.auth-switch').addEventListener('click', () => {
+        const form = document.querySelector('.auth-form');
+        setAuthMode(form.dataset.mode === 'register' ? 'login' : 'register');
+    });
+    document.querySelector('.logout-btn').addEventListener('click', logout);
+
+    // Si no hay sesión el árbol de carpetas responde 401 y se pasa al login
+    showApp();
+
     //save note button event listener
     const cardContainer = document.querySelector('.cards-container');
     cardContainer.addEventListener('click', (e) => {
@@ -28,6 +39,9 @@ document.addEventListener('DOMContentLoaded', () => {
             console.log('Note card found:', noteCard);
             createOrUpdateNote(noteCard);
         }
+        if (e.target.classList.contains('preview-btn')) {
+            togglePreview(e.target.closest('.note-card'));
+        }
         if (e.target.classList.contains('delete-note-btn')) {
             const noteCard = e.target.closest('.note-card');
             deleteNoteCard(noteCard);
@@ -38,6 +52,112 @@ document.addEventListener('DOMContentLoaded', () => {
 
 //----- Event listeners handler functions ---------//
 
+// api es fetch para las rutas que piden sesión. Con 401 (no hay sesión o
+// venció) muestra el login; la respuesta se devuelve igual, así que el que
+// llama sigue mirando response.ok.
+async function api(url, options){
+    const response = await fetch(url, options);
+    if (response.status === 401) {
+        showAuthView();
+    }
+    return response;
+}
+
+function showAuthView(){
+    document.querySelector('.container').hidden = true;
+    document.querySelector('.auth-view').hidden = false;
+    document.querySelector('.auth-username').focus();
+}
+
+// showApp muestra las notas de la sesión actual, sin lo que haya quedado de
+// una sesión anterior.
+function showApp(){
+    document.querySelector('.auth-view').hidden = true;
+    document.querySelector('.container').hidden = false;
+    document.querySelectorAll('.note-card').forEach(card => card.remove());
+    loadFolderTree();
+}
+
+// setAuthMode cambia el formulario entre login y registro; el registro pide
+// además el email.
+function setAuthMode(mode){
+    const form = document.querySelector('.auth-form');
+    const registering = mode === 'register';
+    form.dataset.mode = mode;
+    form.querySelector('.auth-title').textContent = registering ? 'Create an account' : 'Log in';
+    form.querySelector('.auth-submit').textContent = registering ? 'Sign up' : 'Log in';
+    form.querySelector('.auth-switch').textContent = registering ? 'I already have an account' : 'Create an account';
+    form.querySelector('.auth-email').hidden = !registering;
+    form.querySelector('.auth-email').required = registering;
+    form.querySelector('.auth-password').autocomplete = registering ? 'new-password' : 'current-password';
+    showAuthError('');
+}
+
+async function submitAuth(e){
+    e.preventDefault();
+    const form = e.target;
+    const username = form.username.value.trim();
+    const password = form.password.value;
+    try {
+        // Después de registrarse se inicia sesión con los mismos datos
+        if (form.dataset.mode === 'register') {
+            const response = await fetch('/api/v1/register', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ username, email: form.email.value.trim(), password })
+            });
+            if (!response.ok) {
+                showAuthError(await problemMessage(response));
+                return;
+            }
+        }
+        const response = await fetch('/api/v1/login', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ username, password })
+        });
+        if (!response.ok) {
+            showAuthError(await problemMessage(response));
+            return;
+        }
+        form.reset();
+        setAuthMode('login');
+        showApp();
+    } catch (error) {
+        console.error('Error logging in:', error);
+        showAuthError('Could not reach the server');
+    }
+}
+
+async function logout(){
+    try {
+        await fetch('/api/v1/logout', { method: 'POST' });
+    } catch (error) {
+        console.error('Error logging out:', error);
+    }
+    showAuthView();
+}
+
+function showAuthError(message){
+    const error = document.querySelector('.auth-error');
+    error.textContent = message;
+    error.hidden = !message;
+}
+
+// problemMessage arma el texto a mostrar a partir del problem+json del
+// servidor: los errores por campo si los hay, si no el detail.
+async function problemMessage(response){
+    try {
+        const problem = await response.json();
+        if (problem.errors?.length) {
+            return problem.errors.map(e => e.message).join('. ');
+        }
+        return problem.detail || problem.title;
+    } catch {
+        return `Error ${response.status}`;
+    }
+}
+
 // create note card HTML
 function createNote(){
     const cardContainer = document.querySelector('.cards-container');
@@ -49,17 +169,23 @@ function createNote(){
             <button class="delete-note-btn">×</button>
         </div>
         <div class="note-content">
-            <div class="note-body" contenteditable="true" data-placeholder="Take a note..."></div>
+            <div class="note-body" contenteditable="true" data-placeholder="Take a note... (Markdown)"></div>
+            <div class="note-preview" hidden></div>
         </div>
+        <button class="preview-btn">Preview</button>
         <button class="save-btn">Save</button>
     `;
+    // Las notas nuevas se escriben en Markdown
+    newNoteCard.dataset.bodyFormat = 'markdown';
     cardContainer.appendChild(newNoteCard);
     // No es necesario inicializar noteId, undefined es suficiente para la verificación
 }
 
 async function createOrUpdateNote(noteCard){
     const title = noteCard.querySelector('.note-title').textContent.trim();
-    const body = noteCard.querySelector('.note-body').textContent.trim();
+    // innerText respeta los saltos de línea, que en Markdown importan
+    const body = noteCard.querySelector('.note-body').innerText.trim();
+    const body_format = noteCard.dataset.bodyFormat;
     
     console.log('Saving note...', { title, body, hasId: !!noteCard.dataset.noteId });
     
@@ -68,29 +194,44 @@ async function createOrUpdateNote(noteCard){
         if (!noteCard.dataset.noteId) {
             console.log('Creating new note...');
             // CREATE
-            const response = await fetch('/api/notes', {
+            const response = await api('/api/v1/notes', {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({ title, body })
+                body: JSON.stringify({ title, body, body_format })
             });
             
             console.log('Response status:', response.status);
+            if (!response.ok) {
+                return;
+            }
             const data = await response.json();
             console.log('Full response data:', data);  // Ver qué devuelve exactamente
             
-            noteCard.dataset.noteId = data.ID;  // ID en mayúscula
-            console.log('Note created in DB with ID:', data.ID);
+            noteCard.dataset.noteId = data.id;
+            noteCard.dataset.version = data.version;
+            console.log('Note created in DB with ID:', data.id);
             
         } else {
             console.log('Updating existing note...');
-            // UPDATE
+            // UPDATE: PATCH solo cambia título y cuerpo, la carpeta queda igual
             const noteId = noteCard.dataset.noteId;
-            const response = await fetch(`/api/notes/${noteId}`, {
-                method: 'PUT',
-                headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({ title, body })
+            const response = await api(`/api/v1/notes/${noteId}`, {
+                method: 'PATCH',
+                headers: {
+                    'Content-Type': 'application/merge-patch+json',
+                    'If-Match': `"${noteCard.dataset.version}"`
+                },
+                body: JSON.stringify({ title, body, body_format })
             });
             console.log('Note updated, status:', response.status);
+            if (response.status === 412) {
+                await showServerCopy(noteCard, response);
+                return;
+            }
+            if (!response.ok) {
+                return;
+            }
+            rememberVersion(noteCard, response);
         }
     } catch (error) {
         console.error('Error saving note:', error);
@@ -103,10 +244,20 @@ async function deleteNoteCard(noteCard){
     // Si tiene ID, eliminar de la BD
     if (noteId) {
         try {
-            const response = await fetch(`/api/notes/${noteId}`, {
-                method: 'DELETE'
+            const response = await api(`/api/v1/notes/${noteId}`, {
+                method: 'DELETE',
+                headers: { 'If-Match': `"${noteCard.dataset.version}"` }
             });
             console.log('Note deleted from DB, status:', response.status);
+            if (response.status === 412) {
+                // Alguien la cambió mientras tanto: no se borra
+                await showServerCopy(noteCard, response);
+                return;
+            }
+            if (response.status === 401) {
+                // Sin sesión no se borró nada
+                return;
+            }
         } catch (error) {
             console.error('Error deleting note:', error);
         }
@@ -116,7 +267,106 @@ async function deleteNoteCard(noteCard){
     noteCard.remove();
 }
 
+// rememberVersion guarda el ETag que devolvió el servidor para mandarlo en
+// el próximo If-Match.
+function rememberVersion(noteCard, response){
+    const etag = response.headers.get('ETag');
+    if (etag) {
+        noteCard.dataset.version = etag.replaceAll('"', '');
+    }
+}
+
+// showServerCopy se usa cuando el servidor responde 412: la nota cambió en
+// otra pestaña, así que se muestra la versión guardada en lugar de pisarla.
+async function showServerCopy(noteCard, response){
+    const current = await response.json();
+    console.warn('La nota cambió en otro lado, se muestra la versión guardada');
+    noteCard.querySelector('.note-title').textContent = current.title;
+    noteCard.querySelector('.note-body').innerText = current.body ?? '';
+    noteCard.dataset.bodyFormat = current.body_format;
+    rememberVersion(noteCard, response);
+}
+
+// togglePreview alterna entre el texto y el HTML que arma el servidor con
+// ?format=html. Ese HTML ya viene sanitizado, por eso se puede usar innerHTML.
+async function togglePreview(noteCard){
+    const body = noteCard.querySelector('.note-body');
+    const preview = noteCard.querySelector('.note-preview');
+    const button = noteCard.querySelector('.preview-btn');
+    if (!preview.hidden) {
+        preview.hidden = true;
+        body.hidden = false;
+        button.textContent = 'Preview';
+        return;
+    }
+    // Se muestra lo guardado, así que primero se guarda
+    await createOrUpdateNote(noteCard);
+    if (!noteCard.dataset.noteId) {
+        return;
+    }
+    try {
+        const response = await api(`/api/v1/notes/${noteCard.dataset.noteId}?format=html`);
+        if (!response.ok) {
+            console.log('Could not render note, status:', response.status);
+            return;
+        }
+        preview.innerHTML = await response.text();
+        preview.hidden = false;
+        body.hidden = true;
+        button.textContent = 'Edit';
+    } catch (error) {
+        console.error('Error rendering note:', error);
+    }
+}
+
 function createFolder(){
 
     console.log('Folder created');
-}
\ No newline at end of file
+}
+
+// sidebar: folders tree with note counters
+async function loadFolderTree(){
+    try {
+        const response = await api('/api/v1/folders/tree');
+        if (!response.ok) {
+            console.log('Could not load folder tree, status:', response.status);
+            return;
+        }
+        const tree = await response.json();
+        renderFolderTree(tree);
+    } catch (error) {
+        console.error('Error loading folder tree:', error);
+    }
+}
+
+function renderFolderTree(tree){
+    const folderList = document.querySelector('.folder-list');
+    folderList.innerHTML = '';
+    folderList.appendChild(folderItem('📄', 'All Notes', tree.total_notes, 0));
+
+    const addFolders = (folders, depth) => {
+        folders.forEach(folder => {
+            const item = folderItem('📁', folder.name, folder.total_note_count, depth);
+            item.dataset.folderId = folder.id;
+            folderList.appendChild(item);
+            addFolders(folder.children, depth + 1);
+        });
+    };
+    addFolders(tree.folders, 0);
+}
+
+function folderItem(icon, name, count, depth){
+    const item = document.createElement('div');
+    item.classList.add('folder-item');
+    item.style.paddingLeft = `${12 + depth * 16}px`;
+    item.innerHTML = `
+        <div class="folder-item-left">
+            <span class="folder-icon">${icon}</span>
+            <span class="folder-name"></span>
+        </div>
+        <span class="folder-count">${count}</span>
+    `;
+    // textContent para no interpretar HTML en el nombre
+    item.querySelector('.folder-name').textContent = name;
+    return item;
+}
@@ -0,0 +1,483 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	sqlc "tpeweb.com/servidor-go/db/sqlc"
+)
+
+// Memory es un Store en memoria con la misma semántica que las queries de
+// Postgres: sql.ErrNoRows cuando no hay fila, ErrUniqueViolation en los
+// UNIQUE, error en las claves foráneas y los ON DELETE CASCADE del esquema.
+// Está pensado para los tests y se puede usar desde varias goroutines.
+//
+// Diferencias conocidas: los textos se comparan por bytes (collation C) y
+// la búsqueda full-text es una aproximación de to_tsquery y ts_headline.
+type Memory struct {
+	memQueries
+
+	mu   sync.Mutex
+	data memData
+	// Como las secuencias de Postgres, los IDs no vuelven atrás cuando se
+	// deshace una transacción.
+	seq map[string]int32
+
+	// Now es el CURRENT_TIMESTAMP de las queries; por defecto time.Now.
+	Now func() time.Time
+}
+
+type memData struct {
+	users     map[int32]sqlc.User
+	folders   map[int32]sqlc.Folder
+	notes     map[int32]sqlc.Note
+	tags      map[int32]sqlc.Tag
+	noteTags  map[sqlc.NoteTag]struct{}
+	sessions  map[int32]sqlc.Session
+	revisions map[int32]sqlc.NoteRevision
+	items     map[int32]sqlc.NoteItem
+}
+
+func NewMemory() *Memory {
+	m := &Memory{
+		data: memData{
+			users:     map[int32]sqlc.User{},
+			folders:   map[int32]sqlc.Folder{},
+			notes:     map[int32]sqlc.Note{},
+			tags:      map[int32]sqlc.Tag{},
+			noteTags:  map[sqlc.NoteTag]struct{}{},
+			sessions:  map[int32]sqlc.Session{},
+			revisions: map[int32]sqlc.NoteRevision{},
+			items:     map[int32]sqlc.NoteItem{},
+		},
+		seq: map[string]int32{},
+		Now: time.Now,
+	}
+	m.memQueries = memQueries{m: m}
+	return m
+}
+
+// InTx corre fn con el lock tomado; si fn falla, vuelve los datos al estado
+// anterior.
+func (m *Memory) InTx(ctx context.Context, fn func(q sqlc.Querier) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := m.data.clone()
+	if err := fn(memQueries{m: m, inTx: true}); err != nil {
+		m.data = snapshot
+		return err
+	}
+	return nil
+}
+
+func (m *Memory) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *Memory) PendingMigrations(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (d memData) clone() memData {
+	return memData{
+		users:     maps.Clone(d.users),
+		folders:   maps.Clone(d.folders),
+		notes:     maps.Clone(d.notes),
+		tags:      maps.Clone(d.tags),
+		noteTags:  maps.Clone(d.noteTags),
+		sessions:  maps.Clone(d.sessions),
+		revisions: maps.Clone(d.revisions),
+		items:     maps.Clone(d.items),
+	}
+}
+
+// memQueries implementa sqlc.Querier sobre los datos de m. Dentro de InTx
+// el lock ya está tomado y cada query no lo vuelve a pedir.
+type memQueries struct {
+	m    *Memory
+	inTx bool
+}
+
+var _ Store = (*Memory)(nil)
+
+func (q memQueries) lock() func() {
+	if q.inTx {
+		return func() {}
+	}
+	q.m.mu.Lock()
+	return q.m.mu.Unlock
+}
+
+func (q memQueries) data() *memData {
+	return &q.m.data
+}
+
+// now devuelve la hora con la precisión de timestamptz.
+func (q memQueries) now() time.Time {
+	return q.m.Now().Truncate(time.Microsecond)
+}
+
+func (q memQueries) nextID(table string) int32 {
+	q.m.seq[table]++
+	return q.m.seq[table]
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: true}
+}
+
+// errForeignKey es lo que devuelve Memory donde Postgres rechazaría la fila
+// por una REFERENCES.
+var errForeignKey = errors.New("viola una clave foránea")
+
+func foreignKey(column string) error {
+	return fmt.Errorf("%s: %w", column, errForeignKey)
+}
+
+func unique(constraint string) error {
+	return fmt.Errorf("%s: %w", constraint, ErrUniqueViolation)
+}
+
+// checkLength replica el límite de las columnas VARCHAR(n).
+func checkLength(column, value string, max int) error {
+	if utf8.RuneCountInString(value) > max {
+		return fmt.Errorf("valor demasiado largo para %s (máximo %d caracteres)", column, max)
+	}
+	return nil
+}
+
+// checkIn replica los CHECK (columna IN (...)).
+func checkIn(column, value string, allowed ...string) error {
+	if !slices.Contains(allowed, value) {
+		return fmt.Errorf("%q no es un valor válido para %s", value, column)
+	}
+	return nil
+}
+
+// sortedValues devuelve los valores del mapa que cumplen keep, ordenados por
+// ID para que los resultados no dependan del orden de los mapas.
+func sortedValues[T any](rows map[int32]T, keep func(T) bool) []T {
+	ids := slices.Sorted(maps.Keys(rows))
+	var out []T
+	for _, id := range ids {
+		if keep(rows[id]) {
+			out = append(out, rows[id])
+		}
+	}
+	return out
+}
+
+// sortKey es el valor de la columna de orden de una fila en la paginación
+// por keyset; solo se usa text o time según la columna.
+type sortKey struct {
+	text string
+	time time.Time
+}
+
+func (a sortKey) compare(b sortKey) int {
+	if c := cmp.Compare(a.text, b.text); c != 0 {
+		return c
+	}
+	return a.time.Compare(b.time)
+}
+
+// page es la parte común de los parámetros de ListNotes, ListFolders y
+// ListUsers.
+type page struct {
+	known      bool // sort_by es una columna que la query sabe ordenar
+	hasCursor  bool
+	descending bool
+	cursor     sortKey
+	cursorID   int32
+	size       int32
+}
+
+// paginate ordena rows como el ORDER BY de las queries paginadas, se queda
+// con las filas posteriores al cursor y aplica el LIMIT.
+func paginate[T any](rows []T, p page, key func(T) sortKey, id func(T) int32) []T {
+	compare := func(a, b T) int {
+		if p.known {
+			if c := key(a).compare(key(b)); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(id(a), id(b))
+	}
+	if p.descending {
+		slices.SortStableFunc(rows, func(a, b T) int { return compare(b, a) })
+	} else {
+		slices.SortStableFunc(rows, compare)
+	}
+
+	var out []T
+	for _, row := range rows {
+		if p.hasCursor {
+			if !p.known {
+				return nil
+			}
+			c := key(row).compare(p.cursor)
+			if c == 0 {
+				c = cmp.Compare(id(row), p.cursorID)
+			}
+			if (!p.descending && c <= 0) || (p.descending && c >= 0) {
+				continue
+			}
+		}
+		if int32(len(out)) >= p.size {
+			break
+		}
+		out = append(out, row)
+	}
+	return out
+}
+
+func timeKey(t sql.NullTime) sortKey {
+	return sortKey{time: t.Time}
+}
+
+// Usuarios
+
+func (q memQueries) GetUser(ctx context.Context, id int32) (sqlc.User, error) {
+	defer q.lock()()
+	u, ok := q.data().users[id]
+	if !ok {
+		return sqlc.User{}, sql.ErrNoRows
+	}
+	return u, nil
+}
+
+func (q memQueries) GetUserByUsername(ctx context.Context, username string) (sqlc.User, error) {
+	defer q.lock()()
+	for _, u := range q.data().users {
+		if u.Username == username {
+			return u, nil
+		}
+	}
+	return sqlc.User{}, sql.ErrNoRows
+}
+
+func (q memQueries) GetUserByEmail(ctx context.Context, email string) (sqlc.User, error) {
+	defer q.lock()()
+	for _, u := range q.data().users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return sqlc.User{}, sql.ErrNoRows
+}
+
+func (q memQueries) ListUsers(ctx context.Context, arg sqlc.ListUsersParams) ([]sqlc.ListUsersRow, error) {
+	defer q.lock()()
+	users := sortedValues(q.data().users, func(u sqlc.User) bool { return u.ID == arg.UserID })
+
+	p := page{
+		known:      arg.SortBy == "username" || arg.SortBy == "created_at",
+		hasCursor:  arg.HasCursor,
+		descending: arg.Descending,
+		cursorID:   arg.CursorID,
+		size:       arg.PageSize,
+	}
+	if arg.SortBy == "username" {
+		p.cursor = sortKey{text: arg.CursorText}
+	} else {
+		p.cursor = sortKey{time: arg.CursorTime}
+	}
+	users = paginate(users, p, func(u sqlc.User) sortKey {
+		if arg.SortBy == "username" {
+			return sortKey{text: u.Username}
+		}
+		return timeKey(u.CreatedAt)
+	}, func(u sqlc.User) int32 { return u.ID })
+
+	var rows []sqlc.ListUsersRow
+	for _, u := range users {
+		rows = append(rows, sqlc.ListUsersRow{ID: u.ID, Username: u.Username, Email: u.Email, CreatedAt: u.CreatedAt})
+	}
+	return rows, nil
+}
+
+// checkUser valida los UNIQUE y los largos de users; id es el usuario que se
+// está actualizando (0 si es nuevo).
+func (d *memData) checkUser(id int32, username, email string) error {
+	if err := checkLength("users.username", username, 50); err != nil {
+		return err
+	}
+	if err := checkLength("users.email", email, 100); err != nil {
+		return err
+	}
+	for _, other := range d.users {
+		if other.ID == id {
+			continue
+		}
+		if other.Username == username {
+			return unique("users_username_key")
+		}
+		if other.Email == email {
+			return unique("users_email_key")
+		}
+	}
+	return nil
+}
+
+func (q memQueries) CreateUser(ctx context.Context, arg sqlc.CreateUserParams) (sqlc.CreateUserRow, error) {
+	defer q.lock()()
+	d := q.data()
+	if err := d.checkUser(0, arg.Username, arg.Email); err != nil {
+		return sqlc.CreateUserRow{}, err
+	}
+
+	u := sqlc.User{
+		ID:        q.nextID("users"),
+		Username:  arg.Username,
+		Email:     arg.Email,
+		Password:  arg.Password,
+		CreatedAt: nullTime(q.now()),
+	}
+	d.users[u.ID] = u
+	return sqlc.CreateUserRow{ID: u.ID, Username: u.Username, Email: u.Email, CreatedAt: u.CreatedAt}, nil
+}
+
+func (q memQueries) UpdateUser(ctx context.Context, arg sqlc.UpdateUserParams) error {
+	defer q.lock()()
+	d := q.data()
+	u, ok := d.users[arg.ID]
+	if !ok {
+		return nil
+	}
+	if err := d.checkUser(u.ID, arg.Username, arg.Email); err != nil {
+		return err
+	}
+	u.Username, u.Email, u.Password = arg.Username, arg.Email, arg.Password
+	d.users[u.ID] = u
+	return nil
+}
+
+func (q memQueries) UpdateUserPassword(ctx context.Context, arg sqlc.UpdateUserPasswordParams) error {
+	defer q.lock()()
+	d := q.data()
+	if u, ok := d.users[arg.ID]; ok {
+		u.Password = arg.Password
+		d.users[u.ID] = u
+	}
+	return nil
+}
+
+func (q memQueries) DeleteUser(ctx context.Context, id int32) error {
+	defer q.lock()()
+	q.data().deleteUser(id)
+	return nil
+}
+
+// Sesiones
+
+func (q memQueries) CreateSession(ctx context.Context, arg sqlc.CreateSessionParams) (sqlc.Session, error) {
+	defer q.lock()()
+	d := q.data()
+	if _, ok := d.users[arg.UserID]; !ok {
+		return sqlc.Session{}, foreignKey("sessions.user_id")
+	}
+	if err := checkLength("sessions.token_hash", arg.TokenHash, 64); err != nil {
+		return sqlc.Session{}, err
+	}
+	for _, s := range d.sessions {
+		if s.TokenHash == arg.TokenHash {
+			return sqlc.Session{}, unique("sessions_token_hash_key")
+		}
+	}
+
+	s := sqlc.Session{
+		ID:        q.nextID("sessions"),
+		UserID:    arg.UserID,
+		TokenHash: arg.TokenHash,
+		CreatedAt: q.now(),
+		ExpiresAt: arg.ExpiresAt.Truncate(time.Microsecond),
+	}
+	d.sessions[s.ID] = s
+	return s, nil
+}
+
+func (q memQueries) GetSessionUser(ctx context.Context, tokenHash string) (sqlc.GetSessionUserRow, error) {
+	defer q.lock()()
+	d := q.data()
+	now := q.now()
+	for _, s := range d.sessions {
+		if s.TokenHash != tokenHash || !s.ExpiresAt.After(now) {
+			continue
+		}
+		u := d.users[s.UserID]
+		return sqlc.GetSessionUserRow{ID: u.ID, Username: u.Username, Email: u.Email, ExpiresAt: s.ExpiresAt}, nil
+	}
+	return sqlc.GetSessionUserRow{}, sql.ErrNoRows
+}
+
+func (q memQueries) DeleteSession(ctx context.Context, tokenHash string) error {
+	defer q.lock()()
+	d := q.data()
+	maps.DeleteFunc(d.sessions, func(_ int32, s sqlc.Session) bool { return s.TokenHash == tokenHash })
+	return nil
+}
+
+func (q memQueries) DeleteExpiredSessions(ctx context.Context) error {
+	defer q.lock()()
+	d := q.data()
+	now := q.now()
+	maps.DeleteFunc(d.sessions, func(_ int32, s sqlc.Session) bool { return !s.ExpiresAt.After(now) })
+	return nil
+}
+
+// Borrados en cascada, como los ON DELETE CASCADE del esquema.
+
+func (d *memData) deleteUser(id int32) {
+	maps.DeleteFunc(d.sessions, func(_ int32, s sqlc.Session) bool { return s.UserID == id })
+	for _, t := range d.tags {
+		if t.UserID == id {
+			d.deleteTag(t.ID)
+		}
+	}
+	for _, n := range d.notes {
+		if n.UserID == id {
+			d.deleteNote(n.ID)
+		}
+	}
+	for _, f := range d.folders {
+		if f.UserID == id {
+			d.deleteFolder(f.ID)
+		}
+	}
+	delete(d.users, id)
+}
+
+func (d *memData) deleteFolder(id int32) {
+	for _, f := range d.folders {
+		if f.ParentFolderID.Valid && f.ParentFolderID.Int32 == id {
+			d.deleteFolder(f.ID)
+		}
+	}
+	for _, n := range d.notes {
+		if n.FolderID.Valid && n.FolderID.Int32 == id {
+			d.deleteNote(n.ID)
+		}
+	}
+	delete(d.folders, id)
+}
+
+func (d *memData) deleteNote(id int32) {
+	maps.DeleteFunc(d.noteTags, func(nt sqlc.NoteTag, _ struct{}) bool { return nt.NoteID == id })
+	maps.DeleteFunc(d.revisions, func(_ int32, r sqlc.NoteRevision) bool { return r.NoteID == id })
+	maps.DeleteFunc(d.items, func(_ int32, i sqlc.NoteItem) bool { return i.NoteID == id })
+	delete(d.notes, id)
+}
+
+func (d *memData) deleteTag(id int32) {
+	maps.DeleteFunc(d.noteTags, func(nt sqlc.NoteTag, _ struct{}) bool { return nt.TagID == id })
+	delete(d.tags, id)
+}
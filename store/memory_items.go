@@ -0,0 +1,163 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	sqlc "tpeweb.com/servidor-go/db/sqlc"
+)
+
+// Ítems de las checklists
+
+func (d *memData) checkItem(noteID, indent int32) error {
+	if _, ok := d.notes[noteID]; !ok {
+		return foreignKey("note_item.note_id")
+	}
+	if indent != 0 && indent != 1 {
+		return fmt.Errorf("%d no es un valor válido para note_item.indent", indent)
+	}
+	return nil
+}
+
+func byPosition(a, b sqlc.NoteItem) int {
+	return cmp.Or(cmp.Compare(a.Position, b.Position), cmp.Compare(a.ID, b.ID))
+}
+
+func (q memQueries) LockNote(ctx context.Context, arg sqlc.LockNoteParams) (string, error) {
+	defer q.lock()()
+	n, ok := q.data().activeNote(arg.ID, arg.UserID)
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return n.Kind, nil
+}
+
+func (q memQueries) ListNoteItems(ctx context.Context, noteID int32) ([]sqlc.NoteItem, error) {
+	defer q.lock()()
+	items := sortedValues(q.data().items, func(i sqlc.NoteItem) bool { return i.NoteID == noteID })
+	slices.SortStableFunc(items, byPosition)
+	return items, nil
+}
+
+func (q memQueries) GetNoteItem(ctx context.Context, arg sqlc.GetNoteItemParams) (sqlc.NoteItem, error) {
+	defer q.lock()()
+	i, ok := q.data().items[arg.ID]
+	if !ok || i.NoteID != arg.NoteID {
+		return sqlc.NoteItem{}, sql.ErrNoRows
+	}
+	return i, nil
+}
+
+func (q memQueries) NextNoteItemPosition(ctx context.Context, noteID int32) (int32, error) {
+	defer q.lock()()
+	var next int32
+	for _, i := range q.data().items {
+		if i.NoteID == noteID {
+			next = max(next, i.Position+1)
+		}
+	}
+	return next, nil
+}
+
+func (q memQueries) ShiftNoteItems(ctx context.Context, arg sqlc.ShiftNoteItemsParams) error {
+	defer q.lock()()
+	d := q.data()
+	for id, i := range d.items {
+		if i.NoteID == arg.NoteID && i.Position >= arg.Position {
+			i.Position++
+			d.items[id] = i
+		}
+	}
+	return nil
+}
+
+func (q memQueries) CreateNoteItem(ctx context.Context, arg sqlc.CreateNoteItemParams) (sqlc.NoteItem, error) {
+	defer q.lock()()
+	d := q.data()
+	if err := d.checkItem(arg.NoteID, arg.Indent); err != nil {
+		return sqlc.NoteItem{}, err
+	}
+	i := sqlc.NoteItem{
+		ID:       q.nextID("note_item"),
+		NoteID:   arg.NoteID,
+		Text:     arg.Text,
+		Checked:  arg.Checked,
+		Position: arg.Position,
+		Indent:   arg.Indent,
+	}
+	d.items[i.ID] = i
+	return i, nil
+}
+
+func (q memQueries) UpdateNoteItem(ctx context.Context, arg sqlc.UpdateNoteItemParams) (sqlc.NoteItem, error) {
+	defer q.lock()()
+	d := q.data()
+	i, ok := d.items[arg.ID]
+	if !ok || i.NoteID != arg.NoteID {
+		return sqlc.NoteItem{}, sql.ErrNoRows
+	}
+	if err := d.checkItem(arg.NoteID, arg.Indent); err != nil {
+		return sqlc.NoteItem{}, err
+	}
+	i.Text, i.Checked, i.Indent = arg.Text, arg.Checked, arg.Indent
+	d.items[i.ID] = i
+	return i, nil
+}
+
+func (q memQueries) SetNoteItemPosition(ctx context.Context, arg sqlc.SetNoteItemPositionParams) (int64, error) {
+	defer q.lock()()
+	d := q.data()
+	i, ok := d.items[arg.ID]
+	if !ok || i.NoteID != arg.NoteID {
+		return 0, nil
+	}
+	i.Position = arg.Position
+	d.items[i.ID] = i
+	return 1, nil
+}
+
+func (q memQueries) DeleteNoteItem(ctx context.Context, arg sqlc.DeleteNoteItemParams) (int64, error) {
+	defer q.lock()()
+	d := q.data()
+	i, ok := d.items[arg.ID]
+	if !ok || i.NoteID != arg.NoteID {
+		return 0, nil
+	}
+	delete(d.items, i.ID)
+	return 1, nil
+}
+
+func (q memQueries) DeleteNoteItems(ctx context.Context, noteID int32) error {
+	defer q.lock()()
+	d := q.data()
+	for id, i := range d.items {
+		if i.NoteID == noteID {
+			delete(d.items, id)
+		}
+	}
+	return nil
+}
+
+func (q memQueries) SetNoteContent(ctx context.Context, arg sqlc.SetNoteContentParams) (sqlc.SetNoteContentRow, error) {
+	defer q.lock()()
+	d := q.data()
+	n, ok := d.activeNote(arg.ID, arg.UserID)
+	if !ok || (arg.Version.Valid && n.Version != arg.Version.Int32) {
+		return sqlc.SetNoteContentRow{}, sql.ErrNoRows
+	}
+	if err := d.checkNote(n.UserID, n.Title, arg.BodyFormat, n.FolderID); err != nil {
+		return sqlc.SetNoteContentRow{}, err
+	}
+	if err := checkIn("note.kind", arg.Kind, "text", "checklist"); err != nil {
+		return sqlc.SetNoteContentRow{}, err
+	}
+
+	n.Kind, n.Body, n.BodyFormat = arg.Kind, arg.Body, arg.BodyFormat
+	n.UpdatedAt = nullTime(q.now())
+	n.Version++
+	d.notes[n.ID] = n
+	return sqlc.SetNoteContentRow(noteRow(n)), nil
+}
@@ -0,0 +1,789 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	sqlc "tpeweb.com/servidor-go/db/sqlc"
+)
+
+// Carpetas
+
+func (d *memData) activeFolder(id, userID int32) (sqlc.Folder, bool) {
+	f, ok := d.folders[id]
+	return f, ok && f.UserID == userID && !f.DeletedAt.Valid
+}
+
+func (d *memData) checkFolder(userID int32, name string, parent sql.NullInt32) error {
+	if _, ok := d.users[userID]; !ok {
+		return foreignKey("folder.user_id")
+	}
+	if parent.Valid {
+		if _, ok := d.folders[parent.Int32]; !ok {
+			return foreignKey("folder.parent_folder_id")
+		}
+	}
+	return checkLength("folder.name", name, 255)
+}
+
+// subtree devuelve id y todas sus subcarpetas (a cualquier profundidad) que
+// cumplen keep.
+func (d *memData) subtree(id int32, keep func(sqlc.Folder) bool) []int32 {
+	ids := []int32{id}
+	for i := 0; i < len(ids); i++ {
+		for _, f := range d.folders {
+			if f.ParentFolderID.Valid && f.ParentFolderID.Int32 == ids[i] && keep(f) && !slices.Contains(ids, f.ID) {
+				ids = append(ids, f.ID)
+			}
+		}
+	}
+	return ids
+}
+
+func notDeleted(f sqlc.Folder) bool {
+	return !f.DeletedAt.Valid
+}
+
+func (q memQueries) GetFolder(ctx context.Context, arg sqlc.GetFolderParams) (sqlc.Folder, error) {
+	defer q.lock()()
+	f, ok := q.data().activeFolder(arg.ID, arg.UserID)
+	if !ok {
+		return sqlc.Folder{}, sql.ErrNoRows
+	}
+	return f, nil
+}
+
+func (q memQueries) ListFolders(ctx context.Context, arg sqlc.ListFoldersParams) ([]sqlc.Folder, error) {
+	defer q.lock()()
+	folders := sortedValues(q.data().folders, func(f sqlc.Folder) bool {
+		return f.UserID == arg.UserID && !f.DeletedAt.Valid &&
+			(!arg.ParentFolderID.Valid || f.ParentFolderID == arg.ParentFolderID) &&
+			(!arg.CreatedAfter.Valid || f.CreatedAt.Time.After(arg.CreatedAfter.Time))
+	})
+
+	p := page{
+		known:      arg.SortBy == "name" || arg.SortBy == "created_at",
+		hasCursor:  arg.HasCursor,
+		descending: arg.Descending,
+		cursorID:   arg.CursorID,
+		size:       arg.PageSize,
+	}
+	if arg.SortBy == "name" {
+		p.cursor = sortKey{text: arg.CursorText}
+	} else {
+		p.cursor = sortKey{time: arg.CursorTime}
+	}
+	return paginate(folders, p, func(f sqlc.Folder) sortKey {
+		if arg.SortBy == "name" {
+			return sortKey{text: f.Name}
+		}
+		return timeKey(f.CreatedAt)
+	}, func(f sqlc.Folder) int32 { return f.ID }), nil
+}
+
+func (q memQueries) ListFolderTree(ctx context.Context, userID int32) ([]sqlc.ListFolderTreeRow, error) {
+	defer q.lock()()
+	d := q.data()
+	folders := sortedValues(d.folders, func(f sqlc.Folder) bool {
+		return f.UserID == userID && !f.DeletedAt.Valid
+	})
+	slices.SortStableFunc(folders, func(a, b sqlc.Folder) int { return cmp.Compare(a.Name, b.Name) })
+
+	countNotes := func(folderID int32) int32 {
+		var count int32
+		for _, n := range d.notes {
+			if n.FolderID.Valid && n.FolderID.Int32 == folderID && !n.DeletedAt.Valid {
+				count++
+			}
+		}
+		return count
+	}
+
+	var rows []sqlc.ListFolderTreeRow
+	for _, f := range folders {
+		row := sqlc.ListFolderTreeRow{
+			ID:             f.ID,
+			Name:           f.Name,
+			Description:    f.Description,
+			ParentFolderID: f.ParentFolderID,
+			CreatedAt:      f.CreatedAt,
+			NoteCount:      countNotes(f.ID),
+		}
+		for _, id := range d.subtree(f.ID, notDeleted) {
+			row.TotalNoteCount += countNotes(id)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (q memQueries) CreateFolder(ctx context.Context, arg sqlc.CreateFolderParams) (sqlc.Folder, error) {
+	defer q.lock()()
+	d := q.data()
+	if err := d.checkFolder(arg.UserID, arg.Name, arg.ParentFolderID); err != nil {
+		return sqlc.Folder{}, err
+	}
+
+	f := sqlc.Folder{
+		ID:             q.nextID("folder"),
+		UserID:         arg.UserID,
+		Name:           arg.Name,
+		Description:    arg.Description,
+		ParentFolderID: arg.ParentFolderID,
+		CreatedAt:      nullTime(q.now()),
+		Version:        1,
+	}
+	d.folders[f.ID] = f
+	return f, nil
+}
+
+func (q memQueries) UpdateFolder(ctx context.Context, arg sqlc.UpdateFolderParams) (sqlc.Folder, error) {
+	defer q.lock()()
+	d := q.data()
+	f, ok := d.activeFolder(arg.ID, arg.UserID)
+	if !ok || f.Version != arg.Version {
+		return sqlc.Folder{}, sql.ErrNoRows
+	}
+	if err := d.checkFolder(f.UserID, arg.Name, arg.ParentFolderID); err != nil {
+		return sqlc.Folder{}, err
+	}
+
+	f.Name, f.Description, f.ParentFolderID = arg.Name, arg.Description, arg.ParentFolderID
+	f.Version++
+	d.folders[f.ID] = f
+	return f, nil
+}
+
+func (q memQueries) LockFolders(ctx context.Context, arg sqlc.LockFoldersParams) ([]int32, error) {
+	defer q.lock()()
+	d := q.data()
+	var ids []int32
+	for _, id := range arg.FolderIds {
+		if _, ok := d.activeFolder(id, arg.UserID); ok && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// LockFolderTree no hace nada: dentro de InTx el mutex ya está tomado hasta
+// el final de la transacción.
+func (q memQueries) LockFolderTree(ctx context.Context, userID int32) error {
+	return nil
+}
+
+func (q memQueries) IsFolderDescendant(ctx context.Context, arg sqlc.IsFolderDescendantParams) (bool, error) {
+	defer q.lock()()
+	d := q.data()
+	if _, ok := d.folders[arg.FolderID]; !ok {
+		return false, nil
+	}
+	all := func(sqlc.Folder) bool { return true }
+	return slices.Contains(d.subtree(arg.FolderID, all), arg.CandidateID), nil
+}
+
+func (q memQueries) MoveFolder(ctx context.Context, arg sqlc.MoveFolderParams) (int64, error) {
+	defer q.lock()()
+	d := q.data()
+	f, ok := d.activeFolder(arg.ID, arg.UserID)
+	if !ok {
+		return 0, nil
+	}
+	if err := d.checkFolder(f.UserID, f.Name, arg.ParentFolderID); err != nil {
+		return 0, err
+	}
+
+	f.ParentFolderID = arg.ParentFolderID
+	f.Version++
+	d.folders[f.ID] = f
+	return 1, nil
+}
+
+func (q memQueries) DeleteFolder(ctx context.Context, arg sqlc.DeleteFolderParams) (int64, error) {
+	defer q.lock()()
+	d := q.data()
+	f, ok := d.folders[arg.ID]
+	if !ok || f.UserID != arg.UserID || !f.DeletedAt.Valid {
+		return 0, nil
+	}
+	d.deleteFolder(f.ID)
+	return 1, nil
+}
+
+// Notas
+
+func (d *memData) activeNote(id, userID int32) (sqlc.Note, bool) {
+	n, ok := d.notes[id]
+	return n, ok && n.UserID == userID && !n.DeletedAt.Valid
+}
+
+func (d *memData) checkNote(userID int32, title, bodyFormat string, folderID sql.NullInt32) error {
+	if _, ok := d.users[userID]; !ok {
+		return foreignKey("note.user_id")
+	}
+	if folderID.Valid {
+		if _, ok := d.folders[folderID.Int32]; !ok {
+			return foreignKey("note.folder_id")
+		}
+	}
+	if err := checkIn("note.body_format", bodyFormat, "plain", "markdown"); err != nil {
+		return err
+	}
+	return checkLength("note.title", title, 255)
+}
+
+// noteRow es la fila que devuelven GetNote, CreateNote, UpdateNote y
+// ListNotes; se convierte a cada uno de esos tipos.
+func noteRow(n sqlc.Note) sqlc.GetNoteRow {
+	return sqlc.GetNoteRow{
+		ID:         n.ID,
+		UserID:     n.UserID,
+		FolderID:   n.FolderID,
+		Title:      n.Title,
+		Body:       n.Body,
+		BodyFormat: n.BodyFormat,
+		Kind:       n.Kind,
+		CreatedAt:  n.CreatedAt,
+		UpdatedAt:  n.UpdatedAt,
+		Version:    n.Version,
+	}
+}
+
+func (q memQueries) GetNote(ctx context.Context, arg sqlc.GetNoteParams) (sqlc.GetNoteRow, error) {
+	defer q.lock()()
+	n, ok := q.data().activeNote(arg.ID, arg.UserID)
+	if !ok {
+		return sqlc.GetNoteRow{}, sql.ErrNoRows
+	}
+	return noteRow(n), nil
+}
+
+func (q memQueries) CountNotes(ctx context.Context, userID int32) (sqlc.CountNotesRow, error) {
+	defer q.lock()()
+	var row sqlc.CountNotesRow
+	for _, n := range q.data().notes {
+		if n.UserID != userID || n.DeletedAt.Valid {
+			continue
+		}
+		row.Total++
+		if !n.FolderID.Valid {
+			row.Unfiled++
+		}
+	}
+	return row, nil
+}
+
+// hasTags indica si la nota tiene todas (matchAll) o alguna de las etiquetas.
+func (d *memData) hasTags(noteID int32, names []string, matchAll bool) bool {
+	if len(names) == 0 {
+		return true
+	}
+	found := map[string]bool{}
+	for nt := range d.noteTags {
+		if nt.NoteID != noteID {
+			continue
+		}
+		if name := d.tags[nt.TagID].Name; slices.Contains(names, name) {
+			found[name] = true
+		}
+	}
+	if matchAll {
+		return len(found) >= len(names)
+	}
+	return len(found) >= 1
+}
+
+func (q memQueries) ListNotes(ctx context.Context, arg sqlc.ListNotesParams) ([]sqlc.ListNotesRow, error) {
+	defer q.lock()()
+	d := q.data()
+	notes := sortedValues(d.notes, func(n sqlc.Note) bool {
+		return n.UserID == arg.UserID && !n.DeletedAt.Valid &&
+			(!arg.FolderID.Valid || n.FolderID == arg.FolderID) &&
+			(!arg.CreatedAfter.Valid || n.CreatedAt.Time.After(arg.CreatedAfter.Time)) &&
+			(!arg.UpdatedBefore.Valid || n.UpdatedAt.Time.Before(arg.UpdatedBefore.Time)) &&
+			d.hasTags(n.ID, arg.Tags, arg.MatchAllTags)
+	})
+
+	p := page{
+		known:      arg.SortBy == "title" || arg.SortBy == "created_at" || arg.SortBy == "updated_at",
+		hasCursor:  arg.HasCursor,
+		descending: arg.Descending,
+		cursorID:   arg.CursorID,
+		size:       arg.PageSize,
+	}
+	if arg.SortBy == "title" {
+		p.cursor = sortKey{text: arg.CursorText}
+	} else {
+		p.cursor = sortKey{time: arg.CursorTime}
+	}
+	notes = paginate(notes, p, func(n sqlc.Note) sortKey {
+		switch arg.SortBy {
+		case "title":
+			return sortKey{text: n.Title}
+		case "created_at":
+			return timeKey(n.CreatedAt)
+		default:
+			return timeKey(n.UpdatedAt)
+		}
+	}, func(n sqlc.Note) int32 { return n.ID })
+
+	var rows []sqlc.ListNotesRow
+	for _, n := range notes {
+		rows = append(rows, sqlc.ListNotesRow(noteRow(n)))
+	}
+	return rows, nil
+}
+
+func (q memQueries) CreateNote(ctx context.Context, arg sqlc.CreateNoteParams) (sqlc.CreateNoteRow, error) {
+	defer q.lock()()
+	d := q.data()
+	if err := d.checkNote(arg.UserID, arg.Title, arg.BodyFormat, arg.FolderID); err != nil {
+		return sqlc.CreateNoteRow{}, err
+	}
+	if err := checkIn("note.kind", arg.Kind, "text", "checklist"); err != nil {
+		return sqlc.CreateNoteRow{}, err
+	}
+
+	now := nullTime(q.now())
+	n := sqlc.Note{
+		ID:         q.nextID("note"),
+		UserID:     arg.UserID,
+		FolderID:   arg.FolderID,
+		Title:      arg.Title,
+		Body:       arg.Body,
+		BodyFormat: arg.BodyFormat,
+		Kind:       arg.Kind,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Version:    1,
+	}
+	d.notes[n.ID] = n
+	return sqlc.CreateNoteRow(noteRow(n)), nil
+}
+
+func (q memQueries) UpdateNote(ctx context.Context, arg sqlc.UpdateNoteParams) (sqlc.UpdateNoteRow, error) {
+	defer q.lock()()
+	d := q.data()
+	n, ok := d.activeNote(arg.ID, arg.UserID)
+	if !ok || n.Version != arg.Version {
+		return sqlc.UpdateNoteRow{}, sql.ErrNoRows
+	}
+	if err := d.checkNote(n.UserID, arg.Title, arg.BodyFormat, arg.FolderID); err != nil {
+		return sqlc.UpdateNoteRow{}, err
+	}
+
+	n.Title, n.Body, n.BodyFormat, n.FolderID = arg.Title, arg.Body, arg.BodyFormat, arg.FolderID
+	n.UpdatedAt = nullTime(q.now())
+	n.Version++
+	d.notes[n.ID] = n
+	return sqlc.UpdateNoteRow(noteRow(n)), nil
+}
+
+func (q memQueries) DeleteNote(ctx context.Context, arg sqlc.DeleteNoteParams) (int64, error) {
+	defer q.lock()()
+	d := q.data()
+	n, ok := d.notes[arg.ID]
+	if !ok || n.UserID != arg.UserID || !n.DeletedAt.Valid {
+		return 0, nil
+	}
+	d.deleteNote(n.ID)
+	return 1, nil
+}
+
+// Papelera
+
+func (q memQueries) TrashNote(ctx context.Context, arg sqlc.TrashNoteParams) (int64, error) {
+	defer q.lock()()
+	d := q.data()
+	n, ok := d.activeNote(arg.ID, arg.UserID)
+	if !ok || n.Version != arg.Version {
+		return 0, nil
+	}
+	n.DeletedAt = nullTime(q.now())
+	d.notes[n.ID] = n
+	return 1, nil
+}
+
+func (q memQueries) TrashFolder(ctx context.Context, arg sqlc.TrashFolderParams) (int64, error) {
+	defer q.lock()()
+	d := q.data()
+	f, ok := d.activeFolder(arg.ID, arg.UserID)
+	if !ok || f.Version != arg.Version {
+		return 0, nil
+	}
+
+	deletedAt := nullTime(arg.DeletedAt.Truncate(time.Microsecond))
+	ids := d.subtree(f.ID, notDeleted)
+	for _, n := range d.notes {
+		if n.FolderID.Valid && slices.Contains(ids, n.FolderID.Int32) && !n.DeletedAt.Valid {
+			n.DeletedAt = deletedAt
+			d.notes[n.ID] = n
+		}
+	}
+	for _, id := range ids {
+		f := d.folders[id]
+		f.DeletedAt = deletedAt
+		d.folders[id] = f
+	}
+	return int64(len(ids)), nil
+}
+
+// sameTime es el IS NOT DISTINCT FROM de dos timestamps que pueden ser NULL.
+func sameTime(a, b sql.NullTime) bool {
+	if !a.Valid || !b.Valid {
+		return a.Valid == b.Valid
+	}
+	return a.Time.Equal(b.Time)
+}
+
+func byDeletedAtDesc[T any](deletedAt func(T) time.Time) func(a, b T) int {
+	return func(a, b T) int { return deletedAt(b).Compare(deletedAt(a)) }
+}
+
+func (q memQueries) ListTrashedFolders(ctx context.Context, userID int32) ([]sqlc.Folder, error) {
+	defer q.lock()()
+	d := q.data()
+	folders := sortedValues(d.folders, func(f sqlc.Folder) bool {
+		if f.UserID != userID || !f.DeletedAt.Valid {
+			return false
+		}
+		p, ok := d.folders[f.ParentFolderID.Int32]
+		return !f.ParentFolderID.Valid || !ok || !sameTime(p.DeletedAt, f.DeletedAt)
+	})
+	slices.SortStableFunc(folders, byDeletedAtDesc(func(f sqlc.Folder) time.Time { return f.DeletedAt.Time }))
+	return folders, nil
+}
+
+func (q memQueries) ListTrashedNotes(ctx context.Context, userID int32) ([]sqlc.ListTrashedNotesRow, error) {
+	defer q.lock()()
+	d := q.data()
+	notes := sortedValues(d.notes, func(n sqlc.Note) bool {
+		if n.UserID != userID || !n.DeletedAt.Valid {
+			return false
+		}
+		f, ok := d.folders[n.FolderID.Int32]
+		return !n.FolderID.Valid || !ok || !sameTime(f.DeletedAt, n.DeletedAt)
+	})
+	slices.SortStableFunc(notes, byDeletedAtDesc(func(n sqlc.Note) time.Time { return n.DeletedAt.Time }))
+
+	var rows []sqlc.ListTrashedNotesRow
+	for _, n := range notes {
+		rows = append(rows, sqlc.ListTrashedNotesRow{
+			ID:         n.ID,
+			UserID:     n.UserID,
+			FolderID:   n.FolderID,
+			Title:      n.Title,
+			Body:       n.Body,
+			BodyFormat: n.BodyFormat,
+			Kind:       n.Kind,
+			CreatedAt:  n.CreatedAt,
+			UpdatedAt:  n.UpdatedAt,
+			DeletedAt:  n.DeletedAt,
+			Version:    n.Version,
+		})
+	}
+	return rows, nil
+}
+
+func (q memQueries) RestoreNote(ctx context.Context, arg sqlc.RestoreNoteParams) (int64, error) {
+	defer q.lock()()
+	d := q.data()
+	n, ok := d.notes[arg.ID]
+	if !ok || n.UserID != arg.UserID || !n.DeletedAt.Valid {
+		return 0, nil
+	}
+	if f, ok := d.folders[n.FolderID.Int32]; !n.FolderID.Valid || !ok || f.DeletedAt.Valid {
+		n.FolderID = sql.NullInt32{}
+	}
+	n.DeletedAt = sql.NullTime{}
+	d.notes[n.ID] = n
+	return 1, nil
+}
+
+func (q memQueries) RestoreFolder(ctx context.Context, arg sqlc.RestoreFolderParams) (int64, error) {
+	defer q.lock()()
+	d := q.data()
+	target, ok := d.folders[arg.ID]
+	if !ok || target.UserID != arg.UserID || !target.DeletedAt.Valid {
+		return 0, nil
+	}
+
+	deletedAt := target.DeletedAt
+	ids := d.subtree(target.ID, func(f sqlc.Folder) bool { return sameTime(f.DeletedAt, deletedAt) })
+	for _, n := range d.notes {
+		if n.FolderID.Valid && slices.Contains(ids, n.FolderID.Int32) && sameTime(n.DeletedAt, deletedAt) {
+			n.DeletedAt = sql.NullTime{}
+			d.notes[n.ID] = n
+		}
+	}
+	if p, ok := d.folders[target.ParentFolderID.Int32]; !ok || p.DeletedAt.Valid {
+		target.ParentFolderID = sql.NullInt32{}
+		d.folders[target.ID] = target
+	}
+	for _, id := range ids {
+		f := d.folders[id]
+		f.DeletedAt = sql.NullTime{}
+		d.folders[id] = f
+	}
+	return int64(len(ids)), nil
+}
+
+func (q memQueries) PurgeTrashedNotes(ctx context.Context, purgeBefore time.Time) (int64, error) {
+	defer q.lock()()
+	d := q.data()
+	var count int64
+	for _, n := range d.notes {
+		if n.DeletedAt.Valid && n.DeletedAt.Time.Before(purgeBefore) {
+			d.deleteNote(n.ID)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (q memQueries) PurgeTrashedFolders(ctx context.Context, purgeBefore time.Time) (int64, error) {
+	defer q.lock()()
+	d := q.data()
+	expired := sortedValues(d.folders, func(f sqlc.Folder) bool {
+		return f.DeletedAt.Valid && f.DeletedAt.Time.Before(purgeBefore)
+	})
+	for _, f := range expired {
+		d.deleteFolder(f.ID)
+	}
+	return int64(len(expired)), nil
+}
+
+// Revisiones
+
+func (q memQueries) SaveNoteRevision(ctx context.Context, arg sqlc.SaveNoteRevisionParams) (int64, error) {
+	defer q.lock()()
+	d := q.data()
+	n, ok := d.activeNote(arg.ID, arg.UserID)
+	if !ok || (n.Title == arg.NewTitle && n.Body == arg.NewBody && n.BodyFormat == arg.NewBodyFormat) {
+		return 0, nil
+	}
+
+	createdAt := n.UpdatedAt
+	if !createdAt.Valid {
+		createdAt = nullTime(q.now())
+	}
+	r := sqlc.NoteRevision{
+		ID:         q.nextID("note_revision"),
+		NoteID:     n.ID,
+		Title:      n.Title,
+		Body:       n.Body,
+		BodyFormat: n.BodyFormat,
+		CreatedAt:  createdAt,
+	}
+	d.revisions[r.ID] = r
+	return 1, nil
+}
+
+func (q memQueries) ListNoteRevisions(ctx context.Context, arg sqlc.ListNoteRevisionsParams) ([]sqlc.ListNoteRevisionsRow, error) {
+	defer q.lock()()
+	d := q.data()
+	if _, ok := d.activeNote(arg.NoteID, arg.UserID); !ok {
+		return nil, nil
+	}
+	revisions := sortedValues(d.revisions, func(r sqlc.NoteRevision) bool { return r.NoteID == arg.NoteID })
+	slices.Reverse(revisions)
+
+	var rows []sqlc.ListNoteRevisionsRow
+	for _, r := range revisions {
+		rows = append(rows, sqlc.ListNoteRevisionsRow{ID: r.ID, NoteID: r.NoteID, Title: r.Title, CreatedAt: r.CreatedAt})
+	}
+	return rows, nil
+}
+
+func (q memQueries) GetNoteRevision(ctx context.Context, arg sqlc.GetNoteRevisionParams) (sqlc.NoteRevision, error) {
+	defer q.lock()()
+	d := q.data()
+	r, ok := d.revisions[arg.ID]
+	if _, active := d.activeNote(arg.NoteID, arg.UserID); !ok || !active || r.NoteID != arg.NoteID {
+		return sqlc.NoteRevision{}, sql.ErrNoRows
+	}
+	return r, nil
+}
+
+func (q memQueries) PruneNoteRevisions(ctx context.Context, arg sqlc.PruneNoteRevisionsParams) (int64, error) {
+	defer q.lock()()
+	if arg.Keep < 0 {
+		return 0, errors.New("LIMIT no puede ser negativo")
+	}
+	d := q.data()
+	revisions := sortedValues(d.revisions, func(r sqlc.NoteRevision) bool { return r.NoteID == arg.NoteID })
+	slices.Reverse(revisions)
+
+	var count int64
+	for _, r := range revisions[min(int(arg.Keep), len(revisions)):] {
+		delete(d.revisions, r.ID)
+		count++
+	}
+	return count, nil
+}
+
+// Etiquetas
+
+func (d *memData) checkTag(id, userID int32, name string) error {
+	if _, ok := d.users[userID]; !ok {
+		return foreignKey("tag.user_id")
+	}
+	if err := checkLength("tag.name", name, 50); err != nil {
+		return err
+	}
+	for _, t := range d.tags {
+		if t.ID != id && t.UserID == userID && t.Name == name {
+			return unique("tag_user_id_name_key")
+		}
+	}
+	return nil
+}
+
+func byName(a, b sqlc.Tag) int {
+	return cmp.Compare(a.Name, b.Name)
+}
+
+func (q memQueries) ListTags(ctx context.Context, userID int32) ([]sqlc.ListTagsRow, error) {
+	defer q.lock()()
+	d := q.data()
+	tags := sortedValues(d.tags, func(t sqlc.Tag) bool { return t.UserID == userID })
+	slices.SortStableFunc(tags, byName)
+
+	var rows []sqlc.ListTagsRow
+	for _, t := range tags {
+		row := sqlc.ListTagsRow{ID: t.ID, Name: t.Name, CreatedAt: t.CreatedAt}
+		for nt := range d.noteTags {
+			if n, ok := d.notes[nt.NoteID]; nt.TagID == t.ID && ok && !n.DeletedAt.Valid {
+				row.NoteCount++
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (q memQueries) GetTag(ctx context.Context, arg sqlc.GetTagParams) (sqlc.Tag, error) {
+	defer q.lock()()
+	t, ok := q.data().tags[arg.ID]
+	if !ok || t.UserID != arg.UserID {
+		return sqlc.Tag{}, sql.ErrNoRows
+	}
+	return t, nil
+}
+
+func (q memQueries) createTag(userID int32, name string) (sqlc.Tag, error) {
+	d := q.data()
+	if err := d.checkTag(0, userID, name); err != nil {
+		return sqlc.Tag{}, err
+	}
+	t := sqlc.Tag{
+		ID:        q.nextID("tag"),
+		UserID:    userID,
+		Name:      name,
+		CreatedAt: nullTime(q.now()),
+	}
+	d.tags[t.ID] = t
+	return t, nil
+}
+
+func (q memQueries) CreateTag(ctx context.Context, arg sqlc.CreateTagParams) (sqlc.Tag, error) {
+	defer q.lock()()
+	return q.createTag(arg.UserID, arg.Name)
+}
+
+func (q memQueries) UpsertTag(ctx context.Context, arg sqlc.UpsertTagParams) (sqlc.Tag, error) {
+	defer q.lock()()
+	for _, t := range q.data().tags {
+		if t.UserID == arg.UserID && t.Name == arg.Name {
+			return t, nil
+		}
+	}
+	return q.createTag(arg.UserID, arg.Name)
+}
+
+func (q memQueries) RenameTag(ctx context.Context, arg sqlc.RenameTagParams) (sqlc.Tag, error) {
+	defer q.lock()()
+	d := q.data()
+	t, ok := d.tags[arg.ID]
+	if !ok || t.UserID != arg.UserID {
+		return sqlc.Tag{}, sql.ErrNoRows
+	}
+	if err := d.checkTag(t.ID, t.UserID, arg.Name); err != nil {
+		return sqlc.Tag{}, err
+	}
+	t.Name = arg.Name
+	d.tags[t.ID] = t
+	return t, nil
+}
+
+func (q memQueries) DeleteTag(ctx context.Context, arg sqlc.DeleteTagParams) (int64, error) {
+	defer q.lock()()
+	d := q.data()
+	t, ok := d.tags[arg.ID]
+	if !ok || t.UserID != arg.UserID {
+		return 0, nil
+	}
+	d.deleteTag(t.ID)
+	return 1, nil
+}
+
+func (q memQueries) MergeNoteTags(ctx context.Context, arg sqlc.MergeNoteTagsParams) error {
+	defer q.lock()()
+	d := q.data()
+	var merged []sqlc.NoteTag
+	for nt := range d.noteTags {
+		if nt.TagID == arg.SourceID {
+			merged = append(merged, sqlc.NoteTag{NoteID: nt.NoteID, TagID: arg.TargetID})
+		}
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	if _, ok := d.tags[arg.TargetID]; !ok {
+		return foreignKey("note_tag.tag_id")
+	}
+	for _, nt := range merged {
+		d.noteTags[nt] = struct{}{}
+	}
+	return nil
+}
+
+func (q memQueries) ListNoteTags(ctx context.Context, noteID int32) ([]sqlc.Tag, error) {
+	defer q.lock()()
+	d := q.data()
+	tags := sortedValues(d.tags, func(t sqlc.Tag) bool {
+		_, ok := d.noteTags[sqlc.NoteTag{NoteID: noteID, TagID: t.ID}]
+		return ok
+	})
+	slices.SortStableFunc(tags, byName)
+	return tags, nil
+}
+
+func (q memQueries) AttachTag(ctx context.Context, arg sqlc.AttachTagParams) error {
+	defer q.lock()()
+	d := q.data()
+	if _, ok := d.notes[arg.NoteID]; !ok {
+		return foreignKey("note_tag.note_id")
+	}
+	if _, ok := d.tags[arg.TagID]; !ok {
+		return foreignKey("note_tag.tag_id")
+	}
+	d.noteTags[sqlc.NoteTag{NoteID: arg.NoteID, TagID: arg.TagID}] = struct{}{}
+	return nil
+}
+
+func (q memQueries) DetachTag(ctx context.Context, arg sqlc.DetachTagParams) (int64, error) {
+	defer q.lock()()
+	d := q.data()
+	nt := sqlc.NoteTag{NoteID: arg.NoteID, TagID: arg.TagID}
+	if _, ok := d.noteTags[nt]; !ok {
+		return 0, nil
+	}
+	delete(d.noteTags, nt)
+	return 1, nil
+}
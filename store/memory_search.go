@@ -0,0 +1,228 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"unicode"
+
+	sqlc "tpeweb.com/servidor-go/db/sqlc"
+)
+
+// Aproximación de la búsqueda full-text de Postgres para Memory. Entiende las
+// consultas que arma el handler de búsqueda: términos unidos con &, cada uno
+// una palabra, una palabra con prefijo (:*) o una frase ('a' <-> 'b'), y
+// opcionalmente negado con !. El ranking pesa 1 cada aparición en el título y
+// 0.4 en el cuerpo, parecido a los pesos A y B de search_vector.
+
+const (
+	startSel     = "[[[" // StartSel de ts_headline
+	stopSel      = "]]]" // StopSel de ts_headline
+	snippetWords = 20    // MaxWords de ts_headline
+)
+
+type tsTerm struct {
+	words  []string
+	prefix bool // la última palabra es un prefijo
+	negate bool
+}
+
+// parseTSQuery interpreta una consulta de to_tsquery. SQLite la usa también
+// para armar la consulta equivalente de FTS5.
+func parseTSQuery(query string) []tsTerm {
+	var terms []tsTerm
+	for _, raw := range strings.Split(query, "&") {
+		raw = strings.TrimSpace(raw)
+		var t tsTerm
+		if strings.HasPrefix(raw, "!") {
+			t.negate = true
+			raw = raw[1:]
+		}
+		raw = strings.TrimSuffix(strings.TrimPrefix(raw, "("), ")")
+		for _, word := range strings.Split(raw, "<->") {
+			word = strings.TrimSpace(word)
+			if strings.HasSuffix(word, ":*") {
+				t.prefix = true
+				word = strings.TrimSuffix(word, ":*")
+			}
+			if word = strings.Trim(word, "'"); word != "" {
+				t.words = append(t.words, strings.ToLower(word))
+			}
+		}
+		if len(t.words) > 0 {
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+// matchWord indica si el lexema word coincide con la palabra i del término.
+func (t tsTerm) matchWord(i int, word string) bool {
+	if t.prefix && i == len(t.words)-1 {
+		return strings.HasPrefix(word, t.words[i])
+	}
+	return word == t.words[i]
+}
+
+// matchAt indica si el término aparece empezando en el lexema pos.
+func (t tsTerm) matchAt(lexemes []string, pos int) bool {
+	if pos+len(t.words) > len(lexemes) {
+		return false
+	}
+	for i := range t.words {
+		if !t.matchWord(i, lexemes[pos+i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// highlights indica si word es alguna de las palabras buscadas, que es lo
+// que ts_headline marca.
+func highlights(terms []tsTerm, word string) bool {
+	for _, t := range terms {
+		if t.negate {
+			continue
+		}
+		for i := range t.words {
+			if t.matchWord(i, word) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// wordSpans devuelve los rangos de bytes de cada palabra del texto, cortando
+// igual que el parser: todo lo que no es letra o número separa.
+func wordSpans(text string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(text)})
+	}
+	return spans
+}
+
+func spanLexemes(text string, spans [][2]int) []string {
+	lexemes := make([]string, len(spans))
+	for i, s := range spans {
+		lexemes[i] = strings.ToLower(text[s[0]:s[1]])
+	}
+	return lexemes
+}
+
+// headline devuelve text[from:to] con las palabras buscadas marcadas.
+func headline(text string, spans [][2]int, from, to int, terms []tsTerm) string {
+	var b strings.Builder
+	last := from
+	for _, s := range spans {
+		if s[0] < from || s[1] > to || !highlights(terms, strings.ToLower(text[s[0]:s[1]])) {
+			continue
+		}
+		b.WriteString(text[last:s[0]])
+		b.WriteString(startSel)
+		b.WriteString(text[s[0]:s[1]])
+		b.WriteString(stopSel)
+		last = s[1]
+	}
+	b.WriteString(text[last:to])
+	return b.String()
+}
+
+// snippet es el fragmento del cuerpo que devuelve la búsqueda: el cuerpo
+// entero si es corto, o snippetWords palabras desde un poco antes de la
+// primera coincidencia.
+func snippet(body string, terms []tsTerm) string {
+	spans := wordSpans(body)
+	if len(spans) <= snippetWords {
+		return headline(body, spans, 0, len(body), terms)
+	}
+	first := 0
+	for i, lexeme := range spanLexemes(body, spans) {
+		if highlights(terms, lexeme) {
+			first = max(i-snippetWords/4, 0)
+			break
+		}
+	}
+	last := min(first+snippetWords, len(spans)) - 1
+	return headline(body, spans, spans[first][0], spans[last][1], terms)
+}
+
+func (q memQueries) SearchNotes(ctx context.Context, arg sqlc.SearchNotesParams) ([]sqlc.SearchNotesRow, error) {
+	defer q.lock()()
+	d := q.data()
+	terms := parseTSQuery(arg.Query)
+
+	var scope []int32
+	if arg.FolderID.Valid {
+		if _, ok := d.activeFolder(arg.FolderID.Int32, arg.UserID); ok {
+			scope = d.subtree(arg.FolderID.Int32, notDeleted)
+		}
+	}
+
+	var rows []sqlc.SearchNotesRow
+	for _, n := range sortedValues(d.notes, func(n sqlc.Note) bool {
+		return n.UserID == arg.UserID && !n.DeletedAt.Valid &&
+			(!arg.FolderID.Valid || (n.FolderID.Valid && slices.Contains(scope, n.FolderID.Int32)))
+	}) {
+		titleLexemes := spanLexemes(n.Title, wordSpans(n.Title))
+		lexemes := append(titleLexemes, spanLexemes(n.Body.String, wordSpans(n.Body.String))...)
+
+		var rank float32
+		matches := len(terms) > 0
+		for _, t := range terms {
+			found := false
+			for pos := range lexemes {
+				if !t.matchAt(lexemes, pos) {
+					continue
+				}
+				found = true
+				if pos < len(titleLexemes) {
+					rank += 1
+				} else {
+					rank += 0.4
+				}
+			}
+			if found == t.negate {
+				matches = false
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+
+		rows = append(rows, sqlc.SearchNotesRow{
+			ID:             n.ID,
+			FolderID:       n.FolderID,
+			Title:          n.Title,
+			UpdatedAt:      n.UpdatedAt,
+			Rank:           rank,
+			TitleHighlight: headline(n.Title, wordSpans(n.Title), 0, len(n.Title), terms),
+			Snippet:        snippet(n.Body.String, terms),
+		})
+	}
+
+	slices.SortStableFunc(rows, func(a, b sqlc.SearchNotesRow) int {
+		if c := cmp.Compare(b.Rank, a.Rank); c != 0 {
+			return c
+		}
+		return b.UpdatedAt.Time.Compare(a.UpdatedAt.Time)
+	})
+	if len(rows) > int(arg.MaxResults) {
+		rows = rows[:max(arg.MaxResults, 0)]
+	}
+	return rows, nil
+}
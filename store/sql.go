@@ -0,0 +1,40 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"tpeweb.com/servidor-go/db/migrations"
+	sqlc "tpeweb.com/servidor-go/db/sqlc"
+)
+
+// SQL es el Store de producción: las queries de sqlc sobre el pool.
+type SQL struct {
+	*sqlc.Queries
+	db *sql.DB
+}
+
+func NewSQL(db *sql.DB) *SQL {
+	return &SQL{Queries: sqlc.New(db), db: db}
+}
+
+func (s *SQL) InTx(ctx context.Context, fn func(q sqlc.Querier) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(s.Queries.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQL) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *SQL) PendingMigrations(ctx context.Context) (int, error) {
+	return migrations.Postgres.Pending(ctx, s.db)
+}
@@ -0,0 +1,267 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io/fs"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"tpeweb.com/servidor-go/db/migrations"
+	"tpeweb.com/servidor-go/db/queries"
+	sqlc "tpeweb.com/servidor-go/db/sqlc"
+)
+
+// SQLite es el Store para correr sin Postgres, con un solo archivo. Usa las
+// mismas queries de sqlc: sqliteDB las pasa al dialecto de SQLite antes de
+// mandarlas y, para las pocas que no se pueden traducir solas, usa la
+// variante de db/queries/sqlite.
+type SQLite struct {
+	sqliteQuerier
+	db *sql.DB
+}
+
+// NewSQLite espera un pool abierto con db.ConnectDB, que activa las foreign
+// keys y las transacciones con BEGIN IMMEDIATE.
+func NewSQLite(db *sql.DB) *SQLite {
+	return &SQLite{sqliteQuerier: sqliteQuerier{sqlc.New(sqliteDB{db})}, db: db}
+}
+
+// sqliteQuerier son las queries de sqlc sobre SQLite, más los métodos que
+// tienen que cambiar los argumentos antes de mandarlos.
+type sqliteQuerier struct {
+	*sqlc.Queries
+}
+
+// SearchNotes recibe la consulta en la sintaxis de to_tsquery, como en
+// Postgres, y la variante de SQLite la espera en la de FTS5.
+func (q sqliteQuerier) SearchNotes(ctx context.Context, arg sqlc.SearchNotesParams) ([]sqlc.SearchNotesRow, error) {
+	arg.Query = ftsQuery(parseTSQuery(arg.Query))
+	return q.Queries.SearchNotes(ctx, arg)
+}
+
+func (s *SQLite) InTx(ctx context.Context, fn func(q sqlc.Querier) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(sqliteQuerier{sqlc.New(sqliteDB{tx})}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLite) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *SQLite) PendingMigrations(ctx context.Context) (int, error) {
+	return migrations.SQLite.Pending(ctx, s.db)
+}
+
+// sqliteTimeFormat es como se guardan las fechas que manda la aplicación: en
+// UTC y con microsegundos. Las que pone la base con strftime('%f') tienen
+// milisegundos, así que sqliteTime saca los ceros del final hasta dejar tres
+// decimales: cada instante se escribe de una sola forma y las comparaciones
+// de texto dan lo mismo que las de fechas, también con los cursores, que
+// vuelven con el valor leído de la base.
+const sqliteTimeFormat = "2006-01-02 15:04:05.000000"
+
+func sqliteTime(t time.Time) string {
+	s := t.UTC().Format(sqliteTimeFormat)
+	for len(s) > len(sqliteTimeFormat)-3 && s[len(s)-1] == '0' {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+var (
+	pgParam = regexp.MustCompile(`\$(\d+)`)
+	pgCast  = regexp.MustCompile(`::[a-z]+(\[\])?`)
+)
+
+// sqliteQuery es una query de sqlc ya pasada a SQLite.
+type sqliteQuery struct {
+	name string
+	text string
+	// multi indica que son varias sentencias, que tienen que aplicarse juntas.
+	multi bool
+}
+
+// sqliteQueries guarda las queries traducidas, por el texto original.
+var sqliteQueries sync.Map
+
+// sqliteVariants lee las variantes embebidas, por nombre de query.
+var sqliteVariants = sync.OnceValue(func() map[string]sqliteQuery {
+	variants := map[string]sqliteQuery{}
+	entries, err := fs.ReadDir(queries.SQLite, "sqlite")
+	if err != nil {
+		panic(err)
+	}
+	for _, entry := range entries {
+		content, err := fs.ReadFile(queries.SQLite, "sqlite/"+entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		var current *sqliteQuery
+		statements := 0
+		flush := func() {
+			if current != nil {
+				current.text = strings.TrimSpace(current.text)
+				current.multi = statements > 1
+				variants[current.name] = *current
+			}
+		}
+		for _, line := range strings.SplitAfter(string(content), "\n") {
+			if name := queryName(line); name != "" {
+				flush()
+				current, statements = &sqliteQuery{name: name}, 0
+			}
+			if current == nil {
+				continue
+			}
+			current.text += line
+			if strings.HasSuffix(strings.TrimSpace(line), ";") {
+				statements++
+			}
+		}
+		flush()
+	}
+	return variants
+})
+
+// queryName devuelve el nombre de la query si line es el encabezado
+// "-- name: Nombre :tipo" que usa sqlc.
+func queryName(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[0] != "--" || fields[1] != "name:" {
+		return ""
+	}
+	return fields[2]
+}
+
+// translate devuelve la query de Postgres en el dialecto de SQLite: la
+// variante si la hay o, si no, la misma query con parámetros ?N, sin casts y
+// con CURRENT_TIMESTAMP como los DEFAULT del esquema.
+func translate(query string) sqliteQuery {
+	if q, ok := sqliteQueries.Load(query); ok {
+		return q.(sqliteQuery)
+	}
+
+	name, _, _ := strings.Cut(query, "\n")
+	name = queryName(name)
+	q, ok := sqliteVariants()[name]
+	if !ok {
+		text := pgParam.ReplaceAllString(query, "?$1")
+		text = pgCast.ReplaceAllString(text, "")
+		text = strings.ReplaceAll(text, "CURRENT_TIMESTAMP", "strftime('%Y-%m-%d %H:%M:%f', 'now')")
+		q = sqliteQuery{name: name, text: text}
+	}
+	sqliteQueries.Store(query, q)
+	return q
+}
+
+// sqliteArgs pasa los argumentos a valores que entiende SQLite: las fechas
+// como texto y los arrays de Postgres como arrays JSON.
+func sqliteArgs(args []any) []any {
+	converted := make([]any, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			converted[i] = sqliteTime(v)
+		case sql.NullTime:
+			if v.Valid {
+				converted[i] = sqliteTime(v.Time)
+			}
+		case *pq.StringArray:
+			converted[i] = jsonArray(*v)
+		case *pq.Int32Array:
+			converted[i] = jsonArray(*v)
+		default:
+			converted[i] = arg
+		}
+	}
+	return converted
+}
+
+func jsonArray[T any](values []T) string {
+	if len(values) == 0 {
+		return "[]"
+	}
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+// ftsQuery arma la consulta de FTS5 equivalente: los términos positivos con
+// AND y los negados con NOT al final. Cada palabra va entre comillas para
+// que no se interprete como operador.
+func ftsQuery(terms []tsTerm) string {
+	var positive, negative []string
+	for _, t := range terms {
+		words := make([]string, len(t.words))
+		for i, w := range t.words {
+			words[i] = strings.ReplaceAll(w, `"`, `""`)
+		}
+		phrase := `"` + strings.Join(words, " ") + `"`
+		if t.prefix {
+			phrase += "*"
+		}
+		if t.negate {
+			negative = append(negative, phrase)
+		} else {
+			positive = append(positive, phrase)
+		}
+	}
+	query := "(" + strings.Join(positive, " AND ") + ")"
+	for _, phrase := range negative {
+		query += " NOT " + phrase
+	}
+	return query
+}
+
+// sqliteDB es el sqlc.DBTX que traduce cada query antes de mandarla a db,
+// que es el pool o una transacción.
+type sqliteDB struct {
+	db sqlc.DBTX
+}
+
+func (s sqliteDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	q := translate(query)
+	args = sqliteArgs(args)
+	db, ok := s.db.(*sql.DB)
+	if !q.multi || !ok {
+		return s.db.ExecContext(ctx, q.text, args...)
+	}
+
+	// Fuera de una transacción, las sentencias se aplican todas o ninguna
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	result, err := tx.ExecContext(ctx, q.text, args...)
+	if err != nil {
+		return nil, err
+	}
+	return result, tx.Commit()
+}
+
+func (s sqliteDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return s.db.PrepareContext(ctx, translate(query).text)
+}
+
+func (s sqliteDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	q := translate(query)
+	return s.db.QueryContext(ctx, q.text, sqliteArgs(args)...)
+}
+
+func (s sqliteDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	q := translate(query)
+	return s.db.QueryRowContext(ctx, q.text, sqliteArgs(args)...)
+}
@@ -0,0 +1,46 @@
+// Package store define el acceso a datos que usan los handlers. Hay tres
+// implementaciones: SQL, sobre Postgres con las queries generadas por sqlc,
+// SQLite, con esas mismas queries traducidas a SQLite, y Memory, en memoria,
+// para los tests.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	sqlc "tpeweb.com/servidor-go/db/sqlc"
+)
+
+// Store son las queries de sqlc más transacciones y los chequeos de estado.
+type Store interface {
+	sqlc.Querier
+
+	// InTx corre fn en una transacción: si fn devuelve error no queda
+	// aplicado nada de lo que hizo con q.
+	InTx(ctx context.Context, fn func(q sqlc.Querier) error) error
+	// Ping verifica que la base conteste.
+	Ping(ctx context.Context) error
+	// PendingMigrations devuelve cuántas migraciones faltan aplicar.
+	PendingMigrations(ctx context.Context) (int, error)
+}
+
+// ErrUniqueViolation es el error de Memory cuando un INSERT o UPDATE
+// rompería una restricción UNIQUE.
+var ErrUniqueViolation = errors.New("valor duplicado")
+
+// IsUniqueViolation indica si err viene de una restricción UNIQUE, en
+// cualquiera de las implementaciones.
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+	}
+	return errors.Is(err, ErrUniqueViolation)
+}
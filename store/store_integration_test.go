@@ -0,0 +1,188 @@
+//go:build integration
+
+package store_test
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tpeweb.com/servidor-go/config"
+	handlerDB "tpeweb.com/servidor-go/db/handlers"
+	"tpeweb.com/servidor-go/db/migrations"
+	sqlc "tpeweb.com/servidor-go/db/sqlc"
+	"tpeweb.com/servidor-go/password"
+	"tpeweb.com/servidor-go/store"
+)
+
+// Los mismos casos que TestMemory sobre las bases de verdad:
+//
+//	go test -tags integration ./store
+//	TEST_DATABASE_URL=postgres://... go test -tags integration ./store
+
+func TestSQLite(t *testing.T) {
+	testStore(t, func(t *testing.T) store.Store {
+		dbCfg := config.Default().Database
+		dbCfg.Driver = config.DriverSQLite
+		dbCfg.URL = filepath.Join(t.TempDir(), "keepnotes.db")
+		return store.NewSQLite(openMigrated(t, dbCfg, migrations.SQLite))
+	})
+}
+
+func TestSQL(t *testing.T) {
+	dbURL := os.Getenv("TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("sin TEST_DATABASE_URL no hay Postgres contra el que correr")
+	}
+	testStore(t, func(t *testing.T) store.Store {
+		dbCfg := config.Default().Database
+		dbCfg.URL = newSchema(t, dbURL)
+		return store.NewSQL(openMigrated(t, dbCfg, migrations.Postgres))
+	})
+}
+
+// legacySchema es el viejo db/schema/schema.sql, que cargaba
+// docker-entrypoint-initdb.d antes de que existieran las migraciones.
+const legacySchema = `
+CREATE TABLE users (
+  id SERIAL PRIMARY KEY,
+  username VARCHAR(50) UNIQUE NOT NULL,
+  email VARCHAR(100) UNIQUE NOT NULL,
+  password VARCHAR(255) NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE folder (
+  id SERIAL PRIMARY KEY,
+  user_id INT REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(255) NOT NULL,
+  description TEXT,
+  parent_folder_id INT REFERENCES folder(id) ON DELETE CASCADE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE note (
+  id SERIAL PRIMARY KEY,
+  folder_id INT REFERENCES folder(id) ON DELETE CASCADE,
+  title VARCHAR(255) NOT NULL,
+  body TEXT,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);`
+
+// TestSQLLegacy migra una base creada con el viejo db/schema: 0001 se
+// registra sin correrla y las demás completan los datos.
+func TestSQLLegacy(t *testing.T) {
+	dbURL := os.Getenv("TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("sin TEST_DATABASE_URL no hay Postgres contra el que correr")
+	}
+	dbCfg := config.Default().Database
+	dbCfg.URL = newSchema(t, dbURL)
+	conn, err := handlerDB.ConnectDB(dbCfg)
+	if err != nil {
+		t.Fatalf("no se pudo abrir la base: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	// Los ids los da SERIAL, que en un schema nuevo arranca en 1
+	ctx := context.Background()
+	if _, err := conn.ExecContext(ctx, legacySchema+`
+INSERT INTO users (username, email, password) VALUES ('ana', 'ana@example.com', 'secreto123');
+INSERT INTO folder (user_id, name) VALUES (1, 'Casa');
+INSERT INTO folder (user_id, name, parent_folder_id) VALUES (NULL, 'Compras', 1);
+INSERT INTO folder (user_id, name) VALUES (NULL, 'Sin dueño');
+INSERT INTO note (folder_id, title, body) VALUES (2, 'Súper', 'pan');
+INSERT INTO note (folder_id, title) VALUES (3, 'Huérfana');
+INSERT INTO note (folder_id, title) VALUES (NULL, 'Suelta');`); err != nil {
+		t.Fatalf("no se pudo crear el esquema viejo: %v", err)
+	}
+
+	applied, err := migrations.Postgres.Up(ctx, conn)
+	if err != nil {
+		t.Fatalf("no se pudieron aplicar las migraciones: %v", err)
+	}
+	if len(applied) == 0 || applied[0].Version != 2 {
+		t.Fatalf("aplicadas = %+v, se esperaba desde la 0002", applied)
+	}
+	if pending, err := migrations.Postgres.Pending(ctx, conn); err != nil || pending != 0 {
+		t.Fatalf("Pending = %d, %v", pending, err)
+	}
+
+	// Compras hereda la dueña de Casa y su nota queda de ana
+	s := store.NewSQL(conn)
+	if _, err := s.GetFolder(ctx, sqlc.GetFolderParams{ID: 2, UserID: 1}); err != nil {
+		t.Fatalf("la subcarpeta no quedó de ana: %v", err)
+	}
+	note, err := s.GetNote(ctx, sqlc.GetNoteParams{ID: 1, UserID: 1})
+	if err != nil {
+		t.Fatalf("la nota no quedó de ana: %v", err)
+	}
+	if note.Title != "Súper" || note.Version != 1 || note.BodyFormat != "plain" || note.Kind != "text" {
+		t.Fatalf("nota migrada = %+v", note)
+	}
+
+	// Lo que no tiene de dónde sacar dueño queda del usuario legacy
+	legacy, err := s.GetUserByUsername(ctx, "legacy")
+	if err != nil {
+		t.Fatalf("no se creó el usuario legacy: %v", err)
+	}
+	if _, err := s.GetFolder(ctx, sqlc.GetFolderParams{ID: 3, UserID: legacy.ID}); err != nil {
+		t.Fatalf("la carpeta sin dueño no quedó de legacy: %v", err)
+	}
+	for _, id := range []int32{2, 3} {
+		if _, err := s.GetNote(ctx, sqlc.GetNoteParams{ID: id, UserID: legacy.ID}); err != nil {
+			t.Fatalf("la nota %d no quedó de legacy: %v", id, err)
+		}
+	}
+	if ok, _, err := password.Verify("", legacy.Password); ok || err != nil {
+		t.Fatalf("Verify de la contraseña de legacy = %v, %v", ok, err)
+	}
+}
+
+func openMigrated(t *testing.T, dbCfg config.Database, dialect *migrations.Dialect) *sql.DB {
+	t.Helper()
+	conn, err := handlerDB.ConnectDB(dbCfg)
+	if err != nil {
+		t.Fatalf("no se pudo abrir la base: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if _, err := dialect.Up(context.Background(), conn); err != nil {
+		t.Fatalf("no se pudieron aplicar las migraciones: %v", err)
+	}
+	return conn
+}
+
+// newSchema crea un schema descartable y devuelve la URL que trabaja solo ahí.
+func newSchema(t *testing.T, dbURL string) string {
+	t.Helper()
+	admin, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	suffix := make([]byte, 6)
+	rand.Read(suffix)
+	schema := "st_" + hex.EncodeToString(suffix)
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("no se pudo crear el schema de prueba: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("no se pudo borrar el schema %s: %v", schema, err)
+		}
+	})
+
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		t.Fatalf("TEST_DATABASE_URL inválida: %v", err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
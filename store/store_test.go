@@ -0,0 +1,283 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	sqlc "tpeweb.com/servidor-go/db/sqlc"
+	"tpeweb.com/servidor-go/store"
+)
+
+func TestMemory(t *testing.T) {
+	testStore(t, func(*testing.T) store.Store { return store.NewMemory() })
+}
+
+// testStore corre los mismos casos contra cualquier implementación, así
+// Memory no se aleja de lo que hacen las bases de verdad. open tiene que
+// devolver un Store vacío y migrado.
+func testStore(t *testing.T, open func(t *testing.T) store.Store) {
+	t.Run("NoRows", func(t *testing.T) { testNoRows(t, open(t)) })
+	t.Run("UniqueViolation", func(t *testing.T) { testUniqueViolation(t, open(t)) })
+	t.Run("DeleteUserCascade", func(t *testing.T) { testDeleteUserCascade(t, open(t)) })
+	t.Run("DeleteFolderCascade", func(t *testing.T) { testDeleteFolderCascade(t, open(t)) })
+	t.Run("InTxRollback", func(t *testing.T) { testInTxRollback(t, open(t)) })
+}
+
+func createUser(t *testing.T, q sqlc.Querier, username string) int32 {
+	t.Helper()
+	user, err := q.CreateUser(context.Background(), sqlc.CreateUserParams{
+		Username: username,
+		Email:    username + "@example.com",
+		Password: "x",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser(%s): %v", username, err)
+	}
+	return user.ID
+}
+
+func createFolder(t *testing.T, q sqlc.Querier, userID int32, name string, parentID sql.NullInt32) sqlc.Folder {
+	t.Helper()
+	folder, err := q.CreateFolder(context.Background(), sqlc.CreateFolderParams{UserID: userID, Name: name, ParentFolderID: parentID})
+	if err != nil {
+		t.Fatalf("CreateFolder(%s): %v", name, err)
+	}
+	return folder
+}
+
+func createNote(t *testing.T, q sqlc.Querier, userID int32, title string, folderID sql.NullInt32) sqlc.CreateNoteRow {
+	t.Helper()
+	note, err := q.CreateNote(context.Background(), sqlc.CreateNoteParams{
+		UserID:     userID,
+		Title:      title,
+		Body:       sql.NullString{String: "- [ ] pan\n", Valid: true},
+		FolderID:   folderID,
+		BodyFormat: "markdown",
+		Kind:       "checklist",
+	})
+	if err != nil {
+		t.Fatalf("CreateNote(%s): %v", title, err)
+	}
+	return note
+}
+
+func createItem(t *testing.T, q sqlc.Querier, noteID int32) sqlc.NoteItem {
+	t.Helper()
+	item, err := q.CreateNoteItem(context.Background(), sqlc.CreateNoteItemParams{NoteID: noteID, Text: "pan"})
+	if err != nil {
+		t.Fatalf("CreateNoteItem: %v", err)
+	}
+	return item
+}
+
+// expectNoRows verifica que la búsqueda de what no encontró nada.
+func expectNoRows(t *testing.T, what string, err error) {
+	t.Helper()
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("%s: err = %v, quería sql.ErrNoRows", what, err)
+	}
+}
+
+func testNoRows(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	ana := createUser(t, s, "ana")
+	beto := createUser(t, s, "beto")
+	note := createNote(t, s, ana, "Compras", sql.NullInt32{})
+
+	_, err := s.GetUser(ctx, 9999)
+	expectNoRows(t, "GetUser", err)
+	_, err = s.GetUserByUsername(ctx, "carla")
+	expectNoRows(t, "GetUserByUsername", err)
+	_, err = s.GetNote(ctx, sqlc.GetNoteParams{ID: 9999, UserID: ana})
+	expectNoRows(t, "GetNote", err)
+	_, err = s.GetNote(ctx, sqlc.GetNoteParams{ID: note.ID, UserID: beto})
+	expectNoRows(t, "GetNote de otro usuario", err)
+	_, err = s.GetFolder(ctx, sqlc.GetFolderParams{ID: 9999, UserID: ana})
+	expectNoRows(t, "GetFolder", err)
+	_, err = s.GetTag(ctx, sqlc.GetTagParams{ID: 9999, UserID: ana})
+	expectNoRows(t, "GetTag", err)
+	_, err = s.GetNoteItem(ctx, sqlc.GetNoteItemParams{ID: 9999, NoteID: note.ID})
+	expectNoRows(t, "GetNoteItem", err)
+	_, err = s.GetSessionUser(ctx, "no-existe")
+	expectNoRows(t, "GetSessionUser", err)
+	_, err = s.LockNote(ctx, sqlc.LockNoteParams{ID: note.ID, UserID: beto})
+	expectNoRows(t, "LockNote de otro usuario", err)
+}
+
+func testUniqueViolation(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	ana := createUser(t, s, "ana")
+	beto := createUser(t, s, "beto")
+
+	_, err := s.CreateUser(ctx, sqlc.CreateUserParams{Username: "ana", Email: "otra@example.com", Password: "x"})
+	if !store.IsUniqueViolation(err) {
+		t.Errorf("CreateUser con username repetido: err = %v", err)
+	}
+	_, err = s.CreateUser(ctx, sqlc.CreateUserParams{Username: "carla", Email: "ana@example.com", Password: "x"})
+	if !store.IsUniqueViolation(err) {
+		t.Errorf("CreateUser con email repetido: err = %v", err)
+	}
+	err = s.UpdateUser(ctx, sqlc.UpdateUserParams{ID: beto, Username: "ana", Email: "beto@example.com", Password: "x"})
+	if !store.IsUniqueViolation(err) {
+		t.Errorf("UpdateUser con username repetido: err = %v", err)
+	}
+
+	if _, err := s.CreateTag(ctx, sqlc.CreateTagParams{UserID: ana, Name: "casa"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.CreateTag(ctx, sqlc.CreateTagParams{UserID: ana, Name: "casa"})
+	if !store.IsUniqueViolation(err) {
+		t.Errorf("CreateTag repetida: err = %v", err)
+	}
+	// El nombre es único por usuario
+	if _, err := s.CreateTag(ctx, sqlc.CreateTagParams{UserID: beto, Name: "casa"}); err != nil {
+		t.Errorf("CreateTag con el nombre de otro usuario: %v", err)
+	}
+}
+
+func testDeleteUserCascade(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	ana := createUser(t, s, "ana")
+	beto := createUser(t, s, "beto")
+
+	folder := createFolder(t, s, ana, "Casa", sql.NullInt32{})
+	note := createNote(t, s, ana, "Compras", sql.NullInt32{Int32: folder.ID, Valid: true})
+	item := createItem(t, s, note.ID)
+	tag, err := s.CreateTag(ctx, sqlc.CreateTagParams{UserID: ana, Name: "casa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AttachTag(ctx, sqlc.AttachTagParams{NoteID: note.ID, TagID: tag.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SaveNoteRevision(ctx, sqlc.SaveNoteRevisionParams{ID: note.ID, UserID: ana, NewTitle: "Súper", NewBodyFormat: "markdown"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateSession(ctx, sqlc.CreateSessionParams{UserID: ana, TokenHash: "token-ana", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	other := createNote(t, s, beto, "De beto", sql.NullInt32{})
+
+	if err := s.DeleteUser(ctx, ana); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.GetUser(ctx, ana)
+	expectNoRows(t, "GetUser", err)
+	_, err = s.GetFolder(ctx, sqlc.GetFolderParams{ID: folder.ID, UserID: ana})
+	expectNoRows(t, "GetFolder", err)
+	_, err = s.GetNote(ctx, sqlc.GetNoteParams{ID: note.ID, UserID: ana})
+	expectNoRows(t, "GetNote", err)
+	_, err = s.GetNoteItem(ctx, sqlc.GetNoteItemParams{ID: item.ID, NoteID: note.ID})
+	expectNoRows(t, "GetNoteItem", err)
+	_, err = s.GetTag(ctx, sqlc.GetTagParams{ID: tag.ID, UserID: ana})
+	expectNoRows(t, "GetTag", err)
+	_, err = s.GetSessionUser(ctx, "token-ana")
+	expectNoRows(t, "GetSessionUser", err)
+	if tags, err := s.ListNoteTags(ctx, note.ID); err != nil || len(tags) != 0 {
+		t.Errorf("ListNoteTags = %v, %v", tags, err)
+	}
+	if revisions, err := s.ListNoteRevisions(ctx, sqlc.ListNoteRevisionsParams{NoteID: note.ID, UserID: ana}); err != nil || len(revisions) != 0 {
+		t.Errorf("ListNoteRevisions = %v, %v", revisions, err)
+	}
+
+	// Lo de otros usuarios no se toca
+	if _, err := s.GetNote(ctx, sqlc.GetNoteParams{ID: other.ID, UserID: beto}); err != nil {
+		t.Errorf("GetNote de beto: %v", err)
+	}
+}
+
+func testDeleteFolderCascade(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	ana := createUser(t, s, "ana")
+
+	parent := createFolder(t, s, ana, "Casa", sql.NullInt32{})
+	child := createFolder(t, s, ana, "Cocina", sql.NullInt32{Int32: parent.ID, Valid: true})
+	note := createNote(t, s, ana, "Compras", sql.NullInt32{Int32: child.ID, Valid: true})
+	item := createItem(t, s, note.ID)
+	outside := createNote(t, s, ana, "Suelta", sql.NullInt32{})
+
+	// El borrado definitivo solo aplica a lo que está en la papelera
+	if n, err := s.DeleteFolder(ctx, sqlc.DeleteFolderParams{ID: parent.ID, UserID: ana}); err != nil || n != 0 {
+		t.Fatalf("DeleteFolder fuera de la papelera = %d, %v", n, err)
+	}
+	if n, err := s.TrashFolder(ctx, sqlc.TrashFolderParams{ID: parent.ID, UserID: ana, Version: parent.Version, DeletedAt: time.Now()}); err != nil || n == 0 {
+		t.Fatalf("TrashFolder = %d, %v", n, err)
+	}
+	if n, err := s.DeleteFolder(ctx, sqlc.DeleteFolderParams{ID: parent.ID, UserID: ana}); err != nil || n != 1 {
+		t.Fatalf("DeleteFolder = %d, %v", n, err)
+	}
+
+	// La subcarpeta, la nota y sus ítems se fueron con la carpeta
+	if folders, err := s.ListTrashedFolders(ctx, ana); err != nil || len(folders) != 0 {
+		t.Errorf("ListTrashedFolders = %v, %v", folders, err)
+	}
+	if notes, err := s.ListTrashedNotes(ctx, ana); err != nil || len(notes) != 0 {
+		t.Errorf("ListTrashedNotes = %v, %v", notes, err)
+	}
+	_, err := s.GetNoteItem(ctx, sqlc.GetNoteItemParams{ID: item.ID, NoteID: note.ID})
+	expectNoRows(t, "GetNoteItem", err)
+	if _, err := s.GetNote(ctx, sqlc.GetNoteParams{ID: outside.ID, UserID: ana}); err != nil {
+		t.Errorf("GetNote de la nota suelta: %v", err)
+	}
+}
+
+func testInTxRollback(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	ana := createUser(t, s, "ana")
+	note := createNote(t, s, ana, "Compras", sql.NullInt32{})
+
+	boom := errors.New("boom")
+	err := s.InTx(ctx, func(q sqlc.Querier) error {
+		createUser(t, q, "beto")
+		createFolder(t, q, ana, "Casa", sql.NullInt32{})
+		createItem(t, q, note.ID)
+		if err := q.DeleteUser(ctx, ana); err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("InTx = %v, quería el error de fn", err)
+	}
+
+	_, err = s.GetUserByUsername(ctx, "beto")
+	expectNoRows(t, "GetUserByUsername de un usuario creado en la transacción", err)
+	if _, err := s.GetUser(ctx, ana); err != nil {
+		t.Errorf("GetUser de un usuario borrado en la transacción: %v", err)
+	}
+	if _, err := s.GetNote(ctx, sqlc.GetNoteParams{ID: note.ID, UserID: ana}); err != nil {
+		t.Errorf("GetNote de una nota borrada en cascada en la transacción: %v", err)
+	}
+	if items, err := s.ListNoteItems(ctx, note.ID); err != nil || len(items) != 0 {
+		t.Errorf("ListNoteItems = %v, %v", items, err)
+	}
+	if tree, err := s.ListFolderTree(ctx, ana); err != nil || len(tree) != 0 {
+		t.Errorf("ListFolderTree = %v, %v", tree, err)
+	}
+
+	// Un error de la base dentro de fn también deshace lo anterior
+	err = s.InTx(ctx, func(q sqlc.Querier) error {
+		createUser(t, q, "carla")
+		_, err := q.CreateUser(ctx, sqlc.CreateUserParams{Username: "ana", Email: "ana2@example.com", Password: "x"})
+		return err
+	})
+	if !store.IsUniqueViolation(err) {
+		t.Fatalf("InTx = %v, quería una violación de UNIQUE", err)
+	}
+	_, err = s.GetUserByUsername(ctx, "carla")
+	expectNoRows(t, "GetUserByUsername de un usuario creado antes del error", err)
+
+	// Y si fn no falla, queda todo aplicado
+	if err := s.InTx(ctx, func(q sqlc.Querier) error {
+		createUser(t, q, "dani")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetUserByUsername(ctx, "dani"); err != nil {
+		t.Errorf("GetUserByUsername después de un InTx exitoso: %v", err)
+	}
+}